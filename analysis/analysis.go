@@ -0,0 +1,198 @@
+package analysis
+
+import (
+	"fmt"
+	"github.com/brunocroh/chip8/cpu"
+	"strings"
+)
+
+// Finding is an instruction worth pointing out.
+type Finding struct {
+	Address uint16
+	Opcode  uint16
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%03X  %04X  %s", f.Address, f.Opcode, Mnemonic(f.Opcode))
+}
+
+// QuirkUse lists the instructions that behave differently with a quirk.
+type QuirkUse struct {
+	Quirk    string // Field of cpu.Quirks
+	Reason   string
+	Findings []Finding
+}
+
+// Report is what can be told about a ROM without running it. Only the
+// instructions Walk reaches are looked at, so sprites and other data don't
+// count as code.
+type Report struct {
+	Size          int
+	Instructions  []uint16     // Addresses of the reachable instructions
+	Platform      cpu.Platform // The oldest platform with every instruction used
+	Extensions    []Finding    // SCHIP and XO-CHIP instructions
+	Unknown       []Finding    // Words no platform decodes
+	Indirect      []Finding    // Bnnn jumps, the code they reach isn't analysed
+	Keys          []Finding    // Ex9E, ExA1 and Fx0A
+	Sound         []Finding    // Fx18 and the XO-CHIP audio instructions
+	SelfModifying []Finding    // I set to code, with memory stores in the program
+	Quirks        []QuirkUse
+}
+
+// schipOpcodes are the SUPER-CHIP instructions missing from CHIP-8 by exact
+// opcode, and schipMnemonics those with x masked out, formatted with x
+var schipOpcodes = map[uint16]string{
+	0x00FB: "SCR",
+	0x00FC: "SCL",
+	0x00FD: "EXIT",
+	0x00FE: "LOW",
+	0x00FF: "HIGH",
+}
+
+var schipMnemonics = map[uint16]string{
+	0xF030: "LD HF, V%X",
+	0xF075: "LD R, V%X",
+	0xF085: "LD V%X, R",
+}
+
+// xoOpcodes and xoMnemonics are the XO-CHIP instructions missing from
+// SUPER-CHIP, the same way
+var xoOpcodes = map[uint16]string{
+	0xF000: "LD I, long",
+	0xF002: "AUDIO",
+}
+
+var xoMnemonics = map[uint16]string{
+	0xF001: "PLANE %X",
+	0xF03A: "PITCH V%X",
+}
+
+// classify returns the platform that introduced opcode and its mnemonic.
+// ok is false for words that no platform decodes.
+func classify(opcode uint16) (cpu.Platform, string, bool) {
+	x := (opcode & 0x0F00) >> 8
+	y := (opcode & 0x00F0) >> 4
+	n := opcode & 0x000F
+
+	switch {
+	case opcode&0xFFF0 == 0x00C0:
+		return cpu.PLATFORM_SCHIP, fmt.Sprintf("SCD %d", n), true
+	case opcode&0xFFF0 == 0x00D0:
+		return cpu.PLATFORM_XOCHIP, fmt.Sprintf("SCU %d", n), true
+	case opcode&0xF00F == 0xD000:
+		return cpu.PLATFORM_SCHIP, fmt.Sprintf("DRW V%X, V%X, 0", x, y), true
+	case opcode&0xF00F == 0x5002:
+		return cpu.PLATFORM_XOCHIP, fmt.Sprintf("SAVE V%X - V%X", x, y), true
+	case opcode&0xF00F == 0x5003:
+		return cpu.PLATFORM_XOCHIP, fmt.Sprintf("LOAD V%X - V%X", x, y), true
+	}
+
+	if m, ok := schipOpcodes[opcode]; ok {
+		return cpu.PLATFORM_SCHIP, m, true
+	}
+	if m, ok := xoOpcodes[opcode]; ok {
+		return cpu.PLATFORM_XOCHIP, m, true
+	}
+	if m, ok := schipMnemonics[opcode&0xF0FF]; ok {
+		return cpu.PLATFORM_SCHIP, fmt.Sprintf(m, x), true
+	}
+	if m, ok := xoMnemonics[opcode&0xF0FF]; ok {
+		return cpu.PLATFORM_XOCHIP, fmt.Sprintf(m, x), true
+	}
+
+	m := cpu.Disassemble(opcode)
+	if strings.HasPrefix(m, "DW ") {
+		return cpu.PLATFORM_CHIP8, m, false
+	}
+	return cpu.PLATFORM_CHIP8, m, true
+}
+
+// Mnemonic disassembles opcode, including the SCHIP and XO-CHIP
+// instructions.
+func Mnemonic(opcode uint16) string {
+	_, m, _ := classify(opcode)
+	return m
+}
+
+// Analyze inspects the ROM at address.
+func Analyze(rom []byte, address uint16) *Report {
+	p := &Program{Rom: rom, Address: address}
+	r := &Report{Size: len(rom), Instructions: Walk(p)}
+
+	code := map[uint16]bool{}
+	for _, pc := range r.Instructions {
+		for i := uint16(0); i < Size(p.Opcode(pc)); i++ {
+			code[pc+i] = true
+		}
+	}
+
+	quirks := map[string]*QuirkUse{}
+	quirk := func(name, reason string, f Finding) {
+		if quirks[name] == nil {
+			quirks[name] = &QuirkUse{Quirk: name, Reason: reason}
+		}
+		quirks[name].Findings = append(quirks[name].Findings, f)
+	}
+
+	var pointers []Finding
+	stores := false
+
+	for _, pc := range r.Instructions {
+		opcode := p.Opcode(pc)
+		f := Finding{pc, opcode}
+		x := (opcode & 0x0F00) >> 8
+		y := (opcode & 0x00F0) >> 4
+
+		platform, _, ok := classify(opcode)
+		switch {
+		case !ok:
+			r.Unknown = append(r.Unknown, f)
+		case platform != cpu.PLATFORM_CHIP8:
+			r.Extensions = append(r.Extensions, f)
+			if platform > r.Platform {
+				r.Platform = platform
+			}
+		}
+
+		switch {
+		case opcode&0xF0FF == 0xE09E, opcode&0xF0FF == 0xE0A1, opcode&0xF0FF == 0xF00A:
+			r.Keys = append(r.Keys, f)
+		case opcode&0xF0FF == 0xF018, opcode == 0xF002, opcode&0xF0FF == 0xF03A:
+			r.Sound = append(r.Sound, f)
+		case opcode&0xF000 == 0xB000:
+			r.Indirect = append(r.Indirect, f)
+			if x != 0 {
+				quirk("JumpVx", "Bxnn jumps with V0 or Vx", f)
+			}
+		case opcode&0xF000 == 0xA000:
+			if code[opcode&0x0FFF] {
+				pointers = append(pointers, f)
+			}
+		case opcode&0xF00F == 0x8006, opcode&0xF00F == 0x800E:
+			if x != y {
+				quirk("ShiftVy", "8xy6/8xyE with x != y shift Vx or Vy", f)
+			}
+		case opcode&0xF00F == 0x8001, opcode&0xF00F == 0x8002, opcode&0xF00F == 0x8003:
+			quirk("VFReset", "8xy1/8xy2/8xy3 may reset VF", f)
+		case opcode&0xF0FF == 0xF055, opcode&0xF0FF == 0xF065:
+			quirk("KeepIndex", "Fx55/Fx65 may leave I unchanged", f)
+			stores = stores || opcode&0xF0FF == 0xF055
+		case opcode&0xF0FF == 0xF033:
+			stores = true
+		}
+	}
+
+	// Pointing I at code is how CHIP-8 programs patch themselves, it only
+	// matters when something is stored through it
+	if stores {
+		r.SelfModifying = pointers
+	}
+
+	for _, name := range []string{"ShiftVy", "KeepIndex", "JumpVx", "VFReset"} {
+		if q, ok := quirks[name]; ok {
+			r.Quirks = append(r.Quirks, *q)
+		}
+	}
+
+	return r
+}
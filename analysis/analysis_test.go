@@ -0,0 +1,125 @@
+package analysis
+
+import (
+	"github.com/brunocroh/chip8/cpu"
+	"testing"
+)
+
+func program(opcodes ...uint16) []byte {
+	rom := make([]byte, 0, len(opcodes)*2)
+	for _, op := range opcodes {
+		rom = append(rom, byte(op>>8), byte(op))
+	}
+	return rom
+}
+
+func TestWalk(t *testing.T) {
+	rom := program(
+		0x2206, // 200 CALL 206
+		0x1204, // 202 JP 204
+		0x1204, // 204 JP 204
+		0x3000, // 206 SE V0, 0
+		0xF000, // 208 LD I, long
+		0x0300, // 20A
+		0x00EE, // 20C RET
+		0xFFFF, // 20E sprite data
+	)
+
+	want := []uint16{0x200, 0x202, 0x204, 0x206, 0x208, 0x20C}
+	got := Walk(&Program{Rom: rom, Address: cpu.START_ADDRESS})
+	if len(got) != len(want) {
+		t.Fatalf("Expected %03X, got %03X", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected %03X, got %03X", want, got)
+			break
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	rom := program(
+		0xA208, // 200 LD I, 208
+		0xF033, // 202 LD B, V0
+		0x8126, // 204 SHR V1, V2
+		0xE1A1, // 206 SKNP V1
+		0xF118, // 208 LD ST, V1
+		0x00FF, // 20A HIGH
+		0xB300, // 20C JP V3, 300
+	)
+
+	r := Analyze(rom, cpu.START_ADDRESS)
+
+	if r.Platform != cpu.PLATFORM_SCHIP {
+		t.Errorf("Expected platform schip, got %s", r.Platform)
+	}
+	if len(r.Keys) != 1 || r.Keys[0].Address != 0x206 {
+		t.Errorf("Expected key usage at 206, got %v", r.Keys)
+	}
+	if len(r.Sound) != 1 || r.Sound[0].Address != 0x208 {
+		t.Errorf("Expected sound usage at 208, got %v", r.Sound)
+	}
+	if len(r.SelfModifying) != 1 || r.SelfModifying[0].Address != 0x200 {
+		t.Errorf("Expected self-modifying hint at 200, got %v", r.SelfModifying)
+	}
+	if len(r.Indirect) != 1 {
+		t.Errorf("Expected one indirect jump, got %v", r.Indirect)
+	}
+
+	quirks := map[string]int{}
+	for _, q := range r.Quirks {
+		quirks[q.Quirk] = len(q.Findings)
+	}
+	if quirks["ShiftVy"] != 1 || quirks["JumpVx"] != 1 || len(quirks) != 2 {
+		t.Errorf("Expected ShiftVy and JumpVx, got %v", quirks)
+	}
+}
+
+func TestAnalyzeXOChip(t *testing.T) {
+	r := Analyze(program(0x5123, 0x00C4, 0x1204), cpu.START_ADDRESS)
+	if r.Platform != cpu.PLATFORM_XOCHIP {
+		t.Errorf("Expected platform xochip, got %s", r.Platform)
+	}
+	if len(r.Extensions) != 2 {
+		t.Errorf("Expected 2 extension instructions, got %v", r.Extensions)
+	}
+}
+
+func TestMnemonic(t *testing.T) {
+	tests := map[uint16]string{
+		0x00C4: "SCD 4",
+		0xF230: "LD HF, V2",
+		0xF201: "PLANE 2",
+		0x5122: "SAVE V1 - V2",
+		0x1234: "JP 0x234",
+	}
+	for opcode, want := range tests {
+		if got := Mnemonic(opcode); got != want {
+			t.Errorf("Expected %04X to be %q, got %q", opcode, want, got)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		opcode   uint16
+		platform cpu.Platform
+		mnemonic string
+		ok       bool
+	}{
+		{0xF002, cpu.PLATFORM_XOCHIP, "AUDIO", true},
+		{0xF102, cpu.PLATFORM_CHIP8, "DW 0xF102", false},
+		{0xF001, cpu.PLATFORM_XOCHIP, "PLANE 0", true},
+		{0xF030, cpu.PLATFORM_SCHIP, "LD HF, V0", true},
+		{0xF03A, cpu.PLATFORM_XOCHIP, "PITCH V0", true},
+		{0xF000, cpu.PLATFORM_XOCHIP, "LD I, long", true},
+		{0x00FE, cpu.PLATFORM_SCHIP, "LOW", true},
+	}
+	for _, test := range tests {
+		platform, mnemonic, ok := classify(test.opcode)
+		if platform != test.platform || mnemonic != test.mnemonic || ok != test.ok {
+			t.Errorf("Expected %04X to be %v %q %v, got %v %q %v", test.opcode, test.platform, test.mnemonic, test.ok, platform, mnemonic, ok)
+		}
+	}
+}
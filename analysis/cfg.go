@@ -0,0 +1,242 @@
+package analysis
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/brunocroh/chip8/cpu"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Edge leads from the end of a block to the start of another. Kind is
+// FLOW_NEXT for fall through and returns from calls, FLOW_JUMP, FLOW_CALL
+// or FLOW_SKIP for the instruction after a skipped one.
+type Edge struct {
+	To   uint16
+	Kind Flow
+}
+
+// Block is a basic block: instructions that always run in a row, entered
+// at the first and left at the last.
+type Block struct {
+	Start        uint16
+	Instructions []uint16
+	Edges        []Edge
+	Indirect     bool // Ends with a Bnnn jump, whose targets are unknown
+}
+
+// Last returns the address of the last instruction.
+func (b *Block) Last() uint16 {
+	return b.Instructions[len(b.Instructions)-1]
+}
+
+// Subroutine is the code reached from the entry point or from a 2nnn call
+// without following calls. Blocks may be shared between subroutines.
+type Subroutine struct {
+	Entry    uint16
+	Blocks   []uint16 // Start addresses
+	Size     int      // Instructions
+	Calls    []uint16 // Subroutines called
+	Callers  []uint16 // Addresses of the calls to it
+	Returns  bool     // Has a reachable 00EE
+	Indirect bool     // Has a Bnnn jump, so it may be incomplete
+}
+
+// Graph is the control-flow graph of a program from cpu.START_ADDRESS.
+type Graph struct {
+	Program     *Program
+	Blocks      map[uint16]*Block
+	Subroutines []*Subroutine // The entry point first, then by address
+}
+
+// BuildGraph splits the instructions Walk reaches into basic blocks. A block
+// starts at the entry point, at jump and call targets, after calls and at
+// both outcomes of a skip, and ends at any instruction other than FLOW_NEXT.
+func BuildGraph(p *Program) *Graph {
+	instructions := Walk(p)
+	reachable := map[uint16]bool{}
+	for _, pc := range instructions {
+		reachable[pc] = true
+	}
+
+	leaders := map[uint16]bool{cpu.START_ADDRESS: true}
+	edges := map[uint16][]Edge{}
+	ends := map[uint16]bool{}
+
+	for _, pc := range instructions {
+		opcode := p.Opcode(pc)
+		next := pc + Size(opcode)
+		flow, target := FlowOf(opcode)
+
+		var out []Edge
+		switch flow {
+		case FLOW_NEXT:
+			continue
+		case FLOW_JUMP:
+			out = []Edge{{target, FLOW_JUMP}}
+		case FLOW_CALL:
+			out = []Edge{{target, FLOW_CALL}, {next, FLOW_NEXT}}
+		case FLOW_SKIP:
+			skipped := next + 2
+			if p.contains(next) {
+				skipped = next + Size(p.Opcode(next))
+			}
+			out = []Edge{{next, FLOW_NEXT}, {skipped, FLOW_SKIP}}
+		}
+
+		ends[pc] = true
+		for _, e := range out {
+			if reachable[e.To] {
+				leaders[e.To] = true
+				edges[pc] = append(edges[pc], e)
+			}
+		}
+	}
+
+	g := &Graph{Program: p, Blocks: map[uint16]*Block{}}
+
+	for _, start := range instructions {
+		if !leaders[start] {
+			continue
+		}
+
+		b := &Block{Start: start}
+		pc := start
+		for {
+			b.Instructions = append(b.Instructions, pc)
+			next := pc + Size(p.Opcode(pc))
+			if ends[pc] || !reachable[next] || leaders[next] {
+				break
+			}
+			pc = next
+		}
+
+		last := b.Last()
+		opcode := p.Opcode(last)
+		next := last + Size(opcode)
+		if flow, _ := FlowOf(opcode); flow == FLOW_INDIRECT {
+			b.Indirect = true
+		} else if ends[last] {
+			b.Edges = edges[last]
+		} else if reachable[next] {
+			b.Edges = []Edge{{next, FLOW_NEXT}}
+		}
+
+		g.Blocks[start] = b
+	}
+
+	g.subroutines()
+	return g
+}
+
+// subroutines collects the blocks of the entry point and of every call
+// target
+func (g *Graph) subroutines() {
+	callers := map[uint16][]uint16{}
+	for _, b := range g.Blocks {
+		for _, e := range b.Edges {
+			if e.Kind == FLOW_CALL {
+				callers[e.To] = append(callers[e.To], b.Last())
+			}
+		}
+	}
+
+	entries := []uint16{}
+	for entry := range callers {
+		if entry != cpu.START_ADDRESS {
+			entries = append(entries, entry)
+		}
+	}
+	sortAddresses(entries)
+	entries = append([]uint16{cpu.START_ADDRESS}, entries...)
+
+	for _, entry := range entries {
+		s := &Subroutine{Entry: entry, Callers: callers[entry]}
+		sortAddresses(s.Callers)
+
+		seen := map[uint16]bool{}
+		calls := map[uint16]bool{}
+		queue := []uint16{entry}
+		for len(queue) > 0 {
+			b := g.Blocks[queue[0]]
+			queue = queue[1:]
+			if b == nil || seen[b.Start] {
+				continue
+			}
+			seen[b.Start] = true
+
+			s.Blocks = append(s.Blocks, b.Start)
+			s.Size += len(b.Instructions)
+			s.Indirect = s.Indirect || b.Indirect
+			if g.Program.Opcode(b.Last()) == 0x00EE {
+				s.Returns = true
+			}
+
+			for _, e := range b.Edges {
+				if e.Kind == FLOW_CALL {
+					calls[e.To] = true
+				} else {
+					queue = append(queue, e.To)
+				}
+			}
+		}
+
+		sortAddresses(s.Blocks)
+		for call := range calls {
+			s.Calls = append(s.Calls, call)
+		}
+		sortAddresses(s.Calls)
+
+		g.Subroutines = append(g.Subroutines, s)
+	}
+}
+
+func sortAddresses(a []uint16) {
+	sort.Slice(a, func(i, j int) bool { return a[i] < a[j] })
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language, a box per block
+// listing its instructions. Calls are dashed, skips labelled and blocks
+// ending with an indirect jump red.
+func (g *Graph) WriteDOT(w io.Writer, name string) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "digraph %q {\n", name)
+	fmt.Fprintln(bw, "\tnode [shape=box fontname=\"monospace\"];")
+
+	starts := make([]uint16, 0, len(g.Blocks))
+	for start := range g.Blocks {
+		starts = append(starts, start)
+	}
+	sortAddresses(starts)
+
+	for _, start := range starts {
+		b := g.Blocks[start]
+
+		var label strings.Builder
+		for _, pc := range b.Instructions {
+			opcode := g.Program.Opcode(pc)
+			// \l left-aligns the line
+			fmt.Fprintf(&label, "%03X  %04X  %s\\l", pc, opcode, Mnemonic(opcode))
+		}
+
+		attrs := ""
+		if b.Indirect {
+			attrs = " color=red"
+		}
+		fmt.Fprintf(bw, "\t\"%03X\" [label=\"%s\"%s];\n", start, label.String(), attrs)
+
+		for _, e := range b.Edges {
+			fmt.Fprintf(bw, "\t\"%03X\" -> \"%03X\"%s;\n", start, e.To, edgeAttrs[e.Kind])
+		}
+	}
+
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+var edgeAttrs = map[Flow]string{
+	FLOW_CALL: " [style=dashed label=\"call\"]",
+	FLOW_SKIP: " [label=\"skip\"]",
+}
@@ -0,0 +1,105 @@
+package analysis
+
+import (
+	"bytes"
+	"github.com/brunocroh/chip8/cpu"
+	"strings"
+	"testing"
+)
+
+func testGraph() *Graph {
+	rom := program(
+		0x220A, // 200 CALL 20A
+		0x3001, // 202 SE V0, 1
+		0x1200, // 204 JP 200
+		0x1208, // 206 JP 208
+		0x1208, // 208 JP 208
+		0x7001, // 20A ADD V0, 1
+		0xE09E, // 20C SKP V0
+		0x00EE, // 20E RET
+		0xB300, // 210 JP V0, 300
+	)
+	return BuildGraph(&Program{Rom: rom, Address: cpu.START_ADDRESS})
+}
+
+func TestBuildGraph(t *testing.T) {
+	g := testGraph()
+
+	blocks := map[uint16][]Edge{
+		0x200: {{0x20A, FLOW_CALL}, {0x202, FLOW_NEXT}},
+		0x202: {{0x204, FLOW_NEXT}, {0x206, FLOW_SKIP}},
+		0x204: {{0x200, FLOW_JUMP}},
+		0x206: {{0x208, FLOW_JUMP}},
+		0x208: {{0x208, FLOW_JUMP}},
+		0x20A: {{0x20E, FLOW_NEXT}, {0x210, FLOW_SKIP}},
+		0x20E: nil,
+		0x210: nil,
+	}
+
+	if len(g.Blocks) != len(blocks) {
+		t.Fatalf("Expected %d blocks, got %d", len(blocks), len(g.Blocks))
+	}
+	for start, edges := range blocks {
+		b := g.Blocks[start]
+		if b == nil {
+			t.Errorf("Expected a block at %03X", start)
+			continue
+		}
+		if len(b.Edges) != len(edges) {
+			t.Errorf("Expected block %03X edges %v, got %v", start, edges, b.Edges)
+			continue
+		}
+		for i := range edges {
+			if b.Edges[i] != edges[i] {
+				t.Errorf("Expected block %03X edges %v, got %v", start, edges, b.Edges)
+				break
+			}
+		}
+	}
+
+	if len(g.Blocks[0x20A].Instructions) != 2 {
+		t.Errorf("Expected block 20A to hold 2 instructions, got %03X", g.Blocks[0x20A].Instructions)
+	}
+	if !g.Blocks[0x210].Indirect {
+		t.Errorf("Expected block 210 to end with an indirect jump")
+	}
+}
+
+func TestSubroutines(t *testing.T) {
+	g := testGraph()
+
+	if len(g.Subroutines) != 2 {
+		t.Fatalf("Expected 2 subroutines, got %d", len(g.Subroutines))
+	}
+
+	main, sub := g.Subroutines[0], g.Subroutines[1]
+	if main.Entry != 0x200 || len(main.Calls) != 1 || main.Calls[0] != 0x20A || main.Returns {
+		t.Errorf("Expected main at 200 calling 20A, got %+v", main)
+	}
+	if sub.Entry != 0x20A || !sub.Returns || !sub.Indirect || sub.Size != 4 {
+		t.Errorf("Expected subroutine at 20A of 4 instructions returning, got %+v", sub)
+	}
+	if len(sub.Callers) != 1 || sub.Callers[0] != 0x200 {
+		t.Errorf("Expected subroutine called from 200, got %03X", sub.Callers)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testGraph().WriteDOT(&buf, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	dot := buf.String()
+	for _, want := range []string{
+		"digraph \"test\" {",
+		"\"200\" -> \"20A\" [style=dashed label=\"call\"];",
+		"\"202\" -> \"206\" [label=\"skip\"];",
+		"\"204\" -> \"200\";",
+		"\"210\" [label=\"210  B300  JP V0, 0x300\\l\" color=red];",
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("Expected DOT output to contain %s, got\n%s", want, dot)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package analysis
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/brunocroh/chip8/cpu"
+	"image"
+	"image/color"
+	"io"
+	"math"
+)
+
+const MEMORY_SIZE = 4096
+
+// WriteCoverage writes the program as an annotated disassembly: how often
+// each word was executed, read and written, and * for instructions Walk
+// reaches that never ran. Other addresses are listed when accessed, e.g.
+// the font or variables past the program. A summary follows.
+func WriteCoverage(w io.Writer, p *Program, cov *cpu.Coverage) error {
+	bw := bufio.NewWriter(w)
+
+	reachable := map[uint16]bool{}
+	for _, pc := range Walk(p) {
+		reachable[pc] = true
+	}
+
+	fmt.Fprintln(bw, "#  exec    read   write    address  opcode  instruction")
+
+	end := int(p.Address) + len(p.Rom)
+	executed := 0
+	for a := 0; a < MEMORY_SIZE; {
+		address := uint16(a)
+		exec, read, write := cov.Exec[a], cov.Read[a], cov.Write[a]
+
+		if !p.contains(address) || a+1 == MEMORY_SIZE {
+			if a >= int(p.Address) && a < end {
+				// Trailing odd byte
+				fmt.Fprintf(bw, "%7s %7s %7s    %03X  %02X\n", count(exec), count(read), count(write), a, p.Rom[a-int(p.Address)])
+			} else if exec+read+write > 0 {
+				fmt.Fprintf(bw, "%7s %7s %7s    %03X\n", count(exec), count(read), count(write), a)
+			}
+			a++
+			continue
+		}
+
+		// Jumps to odd addresses execute words straddling two lines, the
+		// byte before is shown on its own
+		if exec == 0 && cov.Exec[a+1] > 0 && !reachable[address] {
+			fmt.Fprintf(bw, "%7s %7s %7s    %03X  %02X\n", count(exec), count(read), count(write), a, p.Rom[a-int(p.Address)])
+			a++
+			continue
+		}
+
+		opcode := p.Opcode(address)
+		mark := " "
+		if reachable[address] {
+			if exec > 0 {
+				executed++
+			} else {
+				mark = "*"
+			}
+		}
+
+		read += cov.Read[a+1]
+		write += cov.Write[a+1]
+		fmt.Fprintf(bw, "%7s %7s %7s  %s %03X  %04X    %s\n", count(exec), count(read), count(write), mark, a, opcode, Mnemonic(opcode))
+		a += 2
+	}
+
+	if len(reachable) > 0 {
+		fmt.Fprintf(bw, "# %d of %d reachable instructions executed (%d%%)\n", executed, len(reachable), executed*100/len(reachable))
+	}
+
+	return bw.Flush()
+}
+
+func count(n uint32) string {
+	if n == 0 {
+		return "."
+	}
+	return fmt.Sprint(n)
+}
+
+// Heatmap draws the 4 KiB address space as a 64x64 image, a pixel per
+// address from 0x000 row by row, scale times larger. Executed addresses are
+// green, read ones blue and written ones red, brighter the more often.
+// Bytes of the program never accessed are grey, which shows dead code.
+func Heatmap(p *Program, cov *cpu.Coverage, scale int) *image.RGBA {
+	if scale < 1 {
+		scale = 1
+	}
+
+	var maxExec, maxRead, maxWrite uint32
+	for a := 0; a < MEMORY_SIZE; a++ {
+		maxExec = max(maxExec, cov.Exec[a])
+		maxRead = max(maxRead, cov.Read[a])
+		maxWrite = max(maxWrite, cov.Write[a])
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 64*scale, 64*scale))
+	for a := 0; a < MEMORY_SIZE; a++ {
+		// The second byte of an instruction is executed too
+		exec := cov.Exec[a]
+		if a > 0 {
+			exec = max(exec, cov.Exec[a-1])
+		}
+
+		c := color.RGBA{
+			R: heat(cov.Write[a], maxWrite),
+			G: heat(exec, maxExec),
+			B: heat(cov.Read[a], maxRead),
+			A: 0xFF,
+		}
+		inRom := a >= int(p.Address) && a < int(p.Address)+len(p.Rom)
+		if c.R == 0 && c.G == 0 && c.B == 0 && inRom {
+			c = color.RGBA{0x40, 0x40, 0x40, 0xFF}
+		}
+
+		x, y := a%64*scale, a/64*scale
+		for dy := 0; dy < scale; dy++ {
+			for dx := 0; dx < scale; dx++ {
+				img.SetRGBA(x+dx, y+dy, c)
+			}
+		}
+	}
+
+	return img
+}
+
+// heat maps n to a brightness on a log scale, so addresses run once still
+// show next to loops run millions of times
+func heat(n, most uint32) uint8 {
+	if n == 0 {
+		return 0
+	}
+	return uint8(96 + 159*math.Log1p(float64(n))/math.Log1p(float64(most)))
+}
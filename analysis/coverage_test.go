@@ -0,0 +1,76 @@
+package analysis
+
+import (
+	"bytes"
+	"github.com/brunocroh/chip8/cpu"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func testCoverage() (*Program, *cpu.Coverage) {
+	p := &Program{Rom: program(
+		0x3000, // 200 SE V0, 0
+		0x1204, // 202 JP 204
+		0x1204, // 204 JP 204
+	), Address: cpu.START_ADDRESS}
+
+	cov := &cpu.Coverage{}
+	cov.Exec[0x200] = 1
+	cov.Exec[0x204] = 10
+	cov.Read[0x050] = 2
+	cov.Write[0x300] = 1
+
+	return p, cov
+}
+
+func TestWriteCoverage(t *testing.T) {
+	var buf bytes.Buffer
+	p, cov := testCoverage()
+	if err := WriteCoverage(&buf, p, cov); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"      .       2       .    050\n",
+		"      1       .       .    200  3000    SE V0, 0x00\n",
+		"      .       .       .  * 202  1204    JP 0x204\n",
+		"     10       .       .    204  1204    JP 0x204\n",
+		"      .       .       1    300\n",
+		"# 2 of 3 reachable instructions executed (66%)\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected coverage to contain %q, got\n%s", want, out)
+		}
+	}
+}
+
+func TestHeatmap(t *testing.T) {
+	p, cov := testCoverage()
+	img := Heatmap(p, cov, 2)
+
+	if img.Rect.Dx() != 128 || img.Rect.Dy() != 128 {
+		t.Fatalf("Expected a 128x128 image, got %v", img.Rect)
+	}
+
+	pixel := func(address int) color.RGBA {
+		return img.RGBAAt(address%64*2, address/64*2)
+	}
+
+	if c := pixel(0x204); c.G != 0xFF || c.R != 0 || c.B != 0 {
+		t.Errorf("Expected the hottest instruction bright green, got %v", c)
+	}
+	if c := pixel(0x202); c != (color.RGBA{0x40, 0x40, 0x40, 0xFF}) {
+		t.Errorf("Expected unexecuted code grey, got %v", c)
+	}
+	if c := pixel(0x050); c.B == 0 || c.G != 0 {
+		t.Errorf("Expected the read font blue, got %v", c)
+	}
+	if c := pixel(0x300); c.R == 0 {
+		t.Errorf("Expected the written address red, got %v", c)
+	}
+	if c := pixel(0x100); c != (color.RGBA{0, 0, 0, 0xFF}) {
+		t.Errorf("Expected untouched memory black, got %v", c)
+	}
+}
@@ -0,0 +1,122 @@
+package analysis
+
+import (
+	"github.com/brunocroh/chip8/cpu"
+	"sort"
+)
+
+// Program is a ROM as it sits in memory.
+type Program struct {
+	Rom     []byte
+	Address uint16 // Where Rom is loaded
+}
+
+func (p *Program) contains(address uint16) bool {
+	return address >= p.Address && int(address)+1 < int(p.Address)+len(p.Rom)
+}
+
+// Opcode returns the instruction at address, which must be in the ROM.
+func (p *Program) Opcode(address uint16) uint16 {
+	i := address - p.Address
+	return uint16(p.Rom[i])<<8 | uint16(p.Rom[i+1])
+}
+
+// Flow is how an instruction passes control on.
+type Flow uint8
+
+const (
+	FLOW_NEXT     Flow = iota // Continues with the next instruction
+	FLOW_JUMP                 // 1nnn, continues at the target only
+	FLOW_CALL                 // 2nnn, the target and then the next instruction
+	FLOW_RETURN               // 00EE
+	FLOW_SKIP                 // Continues with the next or the one after
+	FLOW_INDIRECT             // Bnnn, the target depends on a register
+	FLOW_EXIT                 // 00FD, SCHIP exit
+)
+
+// Size returns the length in bytes of the instruction, 4 for the XO-CHIP
+// F000 nnnn long load and 2 otherwise.
+func Size(opcode uint16) uint16 {
+	if opcode == 0xF000 {
+		return 4
+	}
+	return 2
+}
+
+// FlowOf classifies opcode and returns its target address for jumps and
+// calls.
+func FlowOf(opcode uint16) (Flow, uint16) {
+	nnn := opcode & 0x0FFF
+
+	switch opcode & 0xF000 {
+	case 0x0000:
+		switch opcode {
+		case 0x00EE:
+			return FLOW_RETURN, 0
+		case 0x00FD:
+			return FLOW_EXIT, 0
+		}
+	case 0x1000:
+		return FLOW_JUMP, nnn
+	case 0x2000:
+		return FLOW_CALL, nnn
+	case 0x3000, 0x4000:
+		return FLOW_SKIP, 0
+	case 0x5000, 0x9000:
+		if opcode&0x000F == 0 {
+			return FLOW_SKIP, 0
+		}
+	case 0xB000:
+		return FLOW_INDIRECT, nnn
+	case 0xE000:
+		if opcode&0x00FF == 0x9E || opcode&0x00FF == 0xA1 {
+			return FLOW_SKIP, 0
+		}
+	}
+
+	return FLOW_NEXT, 0
+}
+
+// Walk follows the control flow of p from cpu.START_ADDRESS and returns the
+// address of every instruction that can run, sorted. Code only reached
+// through Bnnn jumps can't be found, and paths leaving the ROM are not
+// followed.
+func Walk(p *Program) []uint16 {
+	seen := map[uint16]bool{}
+	var order []uint16
+
+	queue := []uint16{cpu.START_ADDRESS}
+	for len(queue) > 0 {
+		pc := queue[0]
+		queue = queue[1:]
+
+		if seen[pc] || !p.contains(pc) {
+			continue
+		}
+		seen[pc] = true
+		order = append(order, pc)
+
+		opcode := p.Opcode(pc)
+		next := pc + Size(opcode)
+		flow, target := FlowOf(opcode)
+
+		switch flow {
+		case FLOW_NEXT:
+			queue = append(queue, next)
+		case FLOW_JUMP:
+			queue = append(queue, target)
+		case FLOW_CALL:
+			queue = append(queue, target, next)
+		case FLOW_SKIP:
+			// The skipped instruction may be a 4 byte long load
+			skipped := next + 2
+			if p.contains(next) {
+				skipped = next + Size(p.Opcode(next))
+			}
+			queue = append(queue, next, skipped)
+		}
+	}
+
+	sort.Slice(order, func(i, j int) bool { return order[i] < order[j] })
+	return order
+}
@@ -0,0 +1,83 @@
+//go:build !js && !wasm
+// +build !js,!wasm
+
+package main
+
+import (
+	"fmt"
+	"github.com/brunocroh/chip8/analysis"
+	"os"
+	"strings"
+)
+
+// cfgCommand prints a summary per subroutine and writes the control-flow
+// graph as a Graphviz DOT file with -dot
+func cfgCommand(args []string) error {
+	fs := newFlagSet("cfg")
+	entry := entryFlag(fs)
+	dot := fs.String("dot", "", "write the control-flow graph in Graphviz DOT to this file, - for stdout")
+	path, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	rom, err := loadRom(path, *entry)
+	if err != nil {
+		return err
+	}
+
+	g := analysis.BuildGraph(&analysis.Program{Rom: rom.Data, Address: rom.Address})
+
+	if *dot == "-" {
+		return g.WriteDOT(os.Stdout, romName)
+	}
+
+	for _, s := range g.Subroutines {
+		printSubroutine(s)
+	}
+
+	if *dot == "" {
+		return nil
+	}
+
+	f, err := os.Create(*dot)
+	if err != nil {
+		return fmt.Errorf("dot: %w", err)
+	}
+	if err := g.WriteDOT(f, romName); err != nil {
+		f.Close()
+		return fmt.Errorf("dot: %w", err)
+	}
+	return f.Close()
+}
+
+func printSubroutine(s *analysis.Subroutine) {
+	name := fmt.Sprintf("sub %03X", s.Entry)
+	if len(s.Callers) == 0 {
+		name = fmt.Sprintf("main %03X", s.Entry)
+	}
+
+	fmt.Printf("%s: %d blocks, %d instructions", name, len(s.Blocks), s.Size)
+	if !s.Returns && len(s.Callers) > 0 {
+		fmt.Print(", never returns")
+	}
+	if s.Indirect {
+		fmt.Print(", indirect jumps")
+	}
+	fmt.Println()
+
+	if len(s.Callers) > 0 {
+		fmt.Printf("  called from %s\n", addresses(s.Callers))
+	}
+	if len(s.Calls) > 0 {
+		fmt.Printf("  calls %s\n", addresses(s.Calls))
+	}
+}
+
+func addresses(list []uint16) string {
+	s := make([]string, len(list))
+	for i, a := range list {
+		s[i] = fmt.Sprintf("%03X", a)
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,34 @@
+//go:build !js && !wasm
+// +build !js,!wasm
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/brunocroh/chip8/cpu"
+	"os"
+)
+
+// disasmCommand prints every word of the ROM as an instruction. Data can't
+// be told apart from code without running it, so sprites show up as odd
+// instructions or DW.
+func disasmCommand(args []string) error {
+	fs := newFlagSet("disasm")
+	entry := entryFlag(fs)
+	path, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	rom, err := loadRom(path, *entry)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	for _, line := range cpu.DisassembleRom(rom.Data, rom.Address) {
+		fmt.Fprintf(w, "%03X  %04X  %s\n", line.Address, line.Opcode, line.Text)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,256 @@
+//go:build !js && !wasm
+// +build !js,!wasm
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/brunocroh/chip8/analysis"
+	"github.com/brunocroh/chip8/cpu"
+	"github.com/brunocroh/chip8/render"
+	"github.com/brunocroh/chip8/utils"
+	"io"
+	"os"
+	"time"
+)
+
+// parseArgs parses the flags of a command taking a single ROM path and
+// returns the path
+func parseArgs(fs *flag.FlagSet, args []string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return "", err
+		}
+		// The flag package already printed the error and usage
+		return "", exitStatus(2)
+	}
+
+	if fs.NArg() != 1 {
+		return "", usageError(fmt.Sprintf("%s takes one ROM path, got %d arguments", fs.Name(), fs.NArg()))
+	}
+
+	return fs.Arg(0), nil
+}
+
+// explicit returns the names of the flags set on the command line, which
+// take precedence over the ROM database
+func explicit(fs *flag.FlagSet) map[string]bool {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
+
+func entryFlag(fs *flag.FlagSet) *string {
+	return fs.String("entry", "", "ROM to load from a zip archive holding several, by name or number")
+}
+
+// loadRom reads the ROM at path, entry picking it in archives, and sets
+// romName, used to name screenshots and recordings and to pick per-ROM
+// keymaps
+func loadRom(path, entry string) (*utils.Rom, error) {
+	rom, err := utils.LoadRomEntry(path, entry)
+
+	// Picking the ROM is up to the command line
+	var archiveErr *utils.ArchiveError
+	if errors.As(err, &archiveErr) {
+		return nil, usageError("-entry: " + err.Error())
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("load rom: %w", err)
+	}
+
+	romName = rom.Name
+	return rom, nil
+}
+
+// detected reports the ROM database entry of rom
+func detected(rom *utils.Rom) {
+	if rom.Info == nil {
+		return
+	}
+
+	_, platform, _ := rom.Info.Platform()
+	fmt.Printf("Detected %s (%s)\n", rom.Info.Name(), platform)
+}
+
+// parsePalette returns the palette named by the -palette flag, or the ROM's
+// colours when the flag wasn't set
+func parsePalette(name string, set map[string]bool, rom *utils.Rom) (render.Palette, error) {
+	if !set["palette"] && rom.Info != nil {
+		if palette, ok := rom.Info.Palette(); ok {
+			return palette, nil
+		}
+	}
+
+	palette, err := render.ParsePalette(name)
+	if err != nil {
+		return render.Palette{}, usageError(err.Error())
+	}
+	return palette, nil
+}
+
+// machineFlags configure the emulated machine
+type machineFlags struct {
+	clock        int
+	ipf          int
+	timing       string
+	platform     string
+	vblank       bool
+	trace        string
+	coverage     string
+	heatmap      string
+	heatmapScale int
+}
+
+func (m *machineFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&m.clock, "clock", 0, "instructions per second, overrides -ipf when set (e.g. 540 for 9 per frame)")
+	fs.IntVar(&m.ipf, "ipf", cpu.DEFAULT_CYCLES_PER_FRAME, "instructions executed per 60 Hz frame")
+	fs.StringVar(&m.timing, "timing", "fixed", "instruction timing: fixed (-ipf per frame) or vip (COSMAC VIP machine cycles)")
+	fs.StringVar(&m.platform, "platform", cpu.PLATFORM_CHIP8.String(), "interpreter quirks: chip8, vip, schip or xochip")
+	fs.BoolVar(&m.vblank, "vblank", false, "Dxyn waits for the next 60 Hz tick (display wait quirk), whatever the platform")
+	fs.StringVar(&m.trace, "trace", "", "write every executed instruction to this file, - for stderr (not with -frontend tty)")
+	fs.StringVar(&m.coverage, "coverage", "", "write the disassembly annotated with how often each address was executed, read and written to this file when done")
+	fs.IntVar(&m.heatmapScale, "heatmap-scale", 8, "heatmap size as a multiple of 64x64, a pixel per address")
+	fs.StringVar(&m.heatmap, "heatmap", "", "save a PNG heatmap of the executed, read and written addresses to this file when done")
+}
+
+// newChip8 returns a machine running rom. Settings not in set, the flags
+// given on the command line, come from the ROM database when rom is in it.
+// done flushes the trace, writes the coverage reports and has to be called
+// once done.
+func (m *machineFlags) newChip8(rom *utils.Rom, set map[string]bool) (chip8 *cpu.Chip8, done func() error, err error) {
+	timing, ok := cpu.ParseTimingMode(m.timing)
+	if !ok {
+		return nil, nil, usageError(fmt.Sprintf("unknown timing mode %q", m.timing))
+	}
+
+	platform, ok := cpu.ParsePlatform(m.platform)
+	if !ok {
+		return nil, nil, usageError(fmt.Sprintf("unknown platform %q", m.platform))
+	}
+
+	quirks := platform.Quirks()
+	if !set["platform"] && rom.Info != nil {
+		quirks = rom.Info.Quirks()
+	}
+	if m.vblank {
+		quirks.VBlankWait = true
+	}
+
+	chip8 = cpu.NewChip8()
+	chip8.Init()
+	chip8.LoadRomAt(rom.Address, rom.Data)
+	chip8.SetTimingMode(timing)
+	chip8.SetQuirks(quirks)
+
+	if m.clock > 0 {
+		chip8.SetCyclesPerFrame((m.clock + 30) / 60)
+	} else if !set["ipf"] && rom.Info != nil && rom.Info.Tickrate > 0 {
+		chip8.SetCyclesPerFrame(rom.Info.Tickrate)
+	} else {
+		chip8.SetCyclesPerFrame(m.ipf)
+	}
+
+	stopTrace := func() error { return nil }
+	if m.trace != "" {
+		stopTrace, err = m.startTrace(chip8)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	stopCoverage := func() error { return nil }
+	if m.coverage != "" || m.heatmap != "" {
+		stopCoverage = m.startCoverage(chip8, rom)
+	}
+
+	done = func() error {
+		err := stopTrace()
+		if coverageErr := stopCoverage(); err == nil {
+			err = coverageErr
+		}
+		return err
+	}
+
+	return chip8, done, nil
+}
+
+// startTrace writes a line per instruction: address, opcode and mnemonic
+func (m *machineFlags) startTrace(chip8 *cpu.Chip8) (func() error, error) {
+	var file io.WriteCloser = os.Stderr
+	if m.trace != "-" {
+		f, err := os.Create(m.trace)
+		if err != nil {
+			return nil, fmt.Errorf("trace: %w", err)
+		}
+		file = f
+	}
+
+	w := bufio.NewWriter(file)
+	chip8.SetTracer(func(pc, opcode uint16) {
+		fmt.Fprintf(w, "%03X  %04X  %s\n", pc, opcode, cpu.Disassemble(opcode))
+	})
+
+	return func() error {
+		chip8.SetTracer(nil)
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("trace: %w", err)
+		}
+		if file == os.Stderr {
+			return nil
+		}
+		return file.Close()
+	}, nil
+}
+
+// startCoverage records memory accesses, written out by the returned
+// function
+func (m *machineFlags) startCoverage(chip8 *cpu.Chip8, rom *utils.Rom) func() error {
+	cov := &cpu.Coverage{}
+	chip8.SetCoverage(cov)
+
+	return func() error {
+		chip8.SetCoverage(nil)
+		program := &analysis.Program{Rom: rom.Data, Address: rom.Address}
+
+		if m.heatmap != "" {
+			if err := render.SavePNG(m.heatmap, analysis.Heatmap(program, cov, m.heatmapScale)); err != nil {
+				return fmt.Errorf("heatmap: %w", err)
+			}
+		}
+
+		if m.coverage == "" {
+			return nil
+		}
+		f, err := os.Create(m.coverage)
+		if err != nil {
+			return fmt.Errorf("coverage: %w", err)
+		}
+		if err := analysis.WriteCoverage(f, program, cov); err != nil {
+			f.Close()
+			return fmt.Errorf("coverage: %w", err)
+		}
+		return f.Close()
+	}
+}
+
+// recordFlags set up GIF recording
+type recordFlags struct {
+	path string
+}
+
+func (r *recordFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&r.path, "record", "", "record an animated GIF to this file from the start")
+	fs.IntVar(&gifScale, "gif-scale", 4, "GIF size as a multiple of 64x32")
+	fs.DurationVar(&gifLimit, "gif-limit", 30*time.Second, "stop recording GIFs after this long, 0 for no limit")
+}
+
+func paletteFlag(fs *flag.FlagSet) *string {
+	return fs.String("palette", render.DefaultPalette.Name, "colour palette: classic, green, amber, lcd, high-contrast or custom #rrggbb,#rrggbb")
+}
@@ -0,0 +1,119 @@
+//go:build !js && !wasm
+// +build !js,!wasm
+
+package main
+
+import (
+	"fmt"
+	"github.com/brunocroh/chip8/analysis"
+	"github.com/brunocroh/chip8/cpu"
+	"github.com/brunocroh/chip8/romdb"
+	"sort"
+	"strings"
+)
+
+func infoCommand(args []string) error {
+	fs := newFlagSet("info")
+	entry := entryFlag(fs)
+	path, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	rom, err := loadRom(path, *entry)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("File:      %s\n", romName)
+	fmt.Printf("Size:      %d bytes\n", len(rom.Data))
+	fmt.Printf("SHA-1:     %s\n", rom.SHA1)
+	if rom.Address != cpu.START_ADDRESS {
+		fmt.Printf("Address:   %#03x\n", rom.Address)
+	}
+
+	if rom.Info == nil {
+		fmt.Println("Database:  unknown ROM")
+	} else {
+		printDatabase(rom.Info)
+	}
+
+	printAnalysis(analysis.Analyze(rom.Data, rom.Address))
+	return nil
+}
+
+func printDatabase(info *romdb.Info) {
+	fmt.Printf("Title:     %s\n", info.Name())
+	if len(info.Authors) > 0 {
+		fmt.Printf("Authors:   %s\n", strings.Join(info.Authors, ", "))
+	}
+	if info.Release != "" {
+		fmt.Printf("Release:   %s\n", info.Release)
+	}
+	fmt.Printf("Platforms: %s\n", strings.Join(info.Platforms, ", "))
+	if info.Tickrate > 0 {
+		fmt.Printf("Tickrate:  %d instructions per frame\n", info.Tickrate)
+	}
+	fmt.Printf("Quirks:    %+v\n", info.Quirks())
+	if len(info.Keys) > 0 {
+		roles := make([]string, 0, len(info.Keys))
+		for role, key := range info.Keys {
+			roles = append(roles, fmt.Sprintf("%s=%X", role, key))
+		}
+		sort.Strings(roles)
+		fmt.Printf("Keys:      %s\n", strings.Join(roles, " "))
+	}
+	if _, ok := info.Palette(); ok {
+		fmt.Printf("Colours:   %s\n", strings.Join(info.Colors.Pixels, ", "))
+	}
+}
+
+// printAnalysis prints what the ROM does without running it, listing the
+// instructions behind every finding
+func printAnalysis(r *analysis.Report) {
+	fmt.Println()
+	fmt.Printf("Code:      %d reachable instructions\n", len(r.Instructions))
+	fmt.Printf("Platform:  %s\n", r.Platform)
+	uses("Keys", r.Keys)
+	uses("Sound", r.Sound)
+	findings("SCHIP and XO-CHIP instructions", r.Extensions)
+	findings("Invalid instructions", r.Unknown)
+	findings("Self-modifying code, I points to instructions", r.SelfModifying)
+	findings("Indirect jumps, the code they reach is not analysed", r.Indirect)
+
+	for _, q := range r.Quirks {
+		fmt.Printf("Quirk %s: %s\n", q.Quirk, q.Reason)
+		listFindings(q.Findings)
+	}
+}
+
+// uses prints whether the ROM uses a feature
+func uses(title string, list []analysis.Finding) {
+	if len(list) == 0 {
+		fmt.Printf("%-10s none\n", title+":")
+		return
+	}
+	fmt.Printf("%-10s yes\n", title+":")
+	listFindings(list)
+}
+
+// findings prints a section when there is something to report
+func findings(title string, list []analysis.Finding) {
+	if len(list) == 0 {
+		return
+	}
+	fmt.Printf("%s:\n", title)
+	listFindings(list)
+}
+
+// listFindings prints the first few findings, the rest as a count
+func listFindings(list []analysis.Finding) {
+	const max = 5
+	for i, f := range list {
+		if i == max {
+			fmt.Printf("  ... %d more\n", len(list)-max)
+			break
+		}
+		fmt.Printf("  %s\n", f)
+	}
+}
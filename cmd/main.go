@@ -4,141 +4,397 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/brunocroh/chip8/cpu"
-	"github.com/brunocroh/chip8/utils"
+	"github.com/brunocroh/chip8/frontend"
+	"github.com/brunocroh/chip8/keymap"
+	"github.com/brunocroh/chip8/render"
+	"github.com/brunocroh/chip8/tty"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
-
-	"github.com/veandco/go-sdl2/sdl"
 )
 
-var keepRunning bool = true
+var palette render.Palette = render.DefaultPalette
+var effects *render.Effects
+var screenshotScale int
+var romName string
+
+// status shows messages on the running frontend, on stderr otherwise
+var status = stderrStatus
+
+func stderrStatus(message string) {
+	fmt.Fprintln(os.Stderr, message)
+}
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"run", "play a ROM (the default when no command is given)", runCommand},
+	{"headless", "run a ROM without display or input, e.g. to record a GIF", headlessCommand},
+	{"disasm", "print the instructions of a ROM", disasmCommand},
+	{"info", "print details about a ROM", infoCommand},
+	{"cfg", "print the subroutines of a ROM and export its control-flow graph", cfgCommand},
+}
+
+// usageError is a mistake on the command line, it exits with status 2
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// exitStatus ends the program with a status, the error was already reported
+type exitStatus int
+
+func (s exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(s))
+}
 
 func main() {
-	ticker := time.NewTicker(time.Second / 500)
-	defer ticker.Stop()
-	romPath := os.Args[1:]
-	fmt.Println("Initiliaze rom:", romPath)
-	time.Sleep(500 * time.Millisecond)
+	args := os.Args[1:]
+	cmd := commands[0]
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "help", "-h", "-help", "--help":
+			usage()
+			return
+		}
+
+		for _, c := range commands {
+			if c.name == args[0] {
+				cmd, args = c, args[1:]
+				break
+			}
+		}
+	}
+
+	var exitErr exitStatus
+	var usageErr usageError
+
+	err := cmd.run(args)
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+	case errors.As(err, &exitErr):
+		os.Exit(int(exitErr))
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(os.Stderr, "chip8 %s: %v\nRun 'chip8 %s -h' for usage.\n", cmd.name, err, cmd.name)
+		os.Exit(2)
+	default:
+		fmt.Fprintf(os.Stderr, "chip8 %s: %v\n", cmd.name, err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: chip8 [command] [flags] <rom>")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.summary)
+	}
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: chip8 %s [flags] <rom>\n", name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
 
-	rom, err := utils.LoadRom(romPath[0])
+func runCommand(args []string) error {
+	var machine machineFlags
+	var record recordFlags
 
+	fs := newFlagSet("run")
+	machine.register(fs)
+	record.register(fs)
+	paletteName := paletteFlag(fs)
+	frontendName := fs.String("frontend", frontend.Default(), "where to run: "+strings.Join(frontend.Available(), ", "))
+	keymapPath := fs.String("keymap", "", "JSON keymap file (see keymap.Config)")
+	scale := fs.Int("scale", 16, "initial window size as a multiple of 64x32")
+	scaling := fs.String("scaling", "fit", "window scaling: fit (largest size that keeps the aspect ratio) or integer (whole multiples only)")
+	persistenceMode := fs.String("persistence", "off", "anti-flicker filter: off, decay[:0-1] or blend[:frames]")
+	filters := fs.String("filters", "", "comma separated CRT filters: scanlines, grid, bloom, curvature")
+	filterScale := fs.Int("filter-scale", render.DEFAULT_FILTER_SCALE, "resolution the filters work at, as a multiple of 64x32")
+	fs.IntVar(&screenshotScale, "screenshot-scale", 8, "screenshot size as a multiple of 64x32")
+	frames := fs.Int("frames", 0, "stop after this many frames (60 per second), 0 to run until quit")
+	modeName := fs.String("tty-mode", "halfblock", "terminal rendering: halfblock (64x16 cells) or braille (32x8 cells)")
+	keyHold := fs.Duration("key-hold", tty.DEFAULT_KEY_HOLD, "how long a terminal key stays pressed after the terminal last sent it")
+
+	entry := entryFlag(fs)
+	path, err := parseArgs(fs, args)
 	if err != nil {
-		fmt.Println("Fail to load rom")
-		return
+		return err
+	}
+
+	// The null frontend can't be quit
+	if *frontendName == "null" && *frames <= 0 {
+		return usageError("the null frontend needs -frames, or use the headless command")
+	}
+
+	// stderr shares the terminal the tty frontend draws on
+	if *frontendName == "tty" && machine.trace == "-" {
+		return usageError("-trace - garbles the tty frontend, trace to a file instead")
+	}
+
+	if *scaling != "fit" && *scaling != "integer" {
+		return usageError(fmt.Sprintf("unknown scaling mode %q", *scaling))
+	}
+
+	mode, ok := tty.ParseMode(*modeName)
+	if !ok {
+		return usageError(fmt.Sprintf("unknown terminal mode %q", *modeName))
+	}
+
+	persistence, err := render.ParsePersistence(*persistenceMode)
+	if err != nil {
+		return usageError(err.Error())
+	}
+
+	effects, err = render.ParseEffects(*filters, *filterScale)
+	if err != nil {
+		return usageError(err.Error())
+	}
+
+	rom, err := loadRom(path, *entry)
+	if err != nil {
+		return err
+	}
+	detected(rom)
+
+	set := explicit(fs)
+	palette, err = parsePalette(*paletteName, set, rom)
+	if err != nil {
+		return err
+	}
+
+	var keymapConfig *keymap.Config
+	if *keymapPath != "" {
+		keymapConfig, err = keymap.Load(*keymapPath)
+		if err != nil {
+			return fmt.Errorf("load keymap: %w", err)
+		}
+	}
+
+	if rom.Info != nil {
+		keymapConfig = keymapConfig.WithHints(keymap.Hints(rom.Info.Keys))
+	}
+
+	keys, err := keymapConfig.Keymap(romName)
+	if err != nil {
+		return err
+	}
+
+	controllerKeys, err := keymapConfig.ControllerKeymap(romName)
+	if err != nil {
+		return err
+	}
+
+	chip8, done, err := machine.newChip8(rom, set)
+	if err != nil {
+		return err
+	}
+
+	fe, err := frontend.Open(*frontendName, frontend.Options{
+		Title:          "CHIP-8 - " + romName,
+		Scale:          *scale,
+		IntegerScaling: *scaling == "integer",
+		Keys:           keys,
+		ControllerKeys: controllerKeys,
+		TerminalMode:   mode,
+		KeyHold:        *keyHold,
+	})
+	if err != nil {
+		done()
+		return fmt.Errorf("open frontend: %w", err)
+	}
+	status = fe.Status
+
+	if record.path != "" {
+		startRecording(record.path)
+	}
+
+	// The null frontend only records, it runs as fast as possible
+	err = run(chip8, fe, persistence, *frontendName != "null", *frames)
+
+	fe.Close()
+	status = stderrStatus
+	if recordErr := stopRecording(); err == nil {
+		err = recordErr
+	}
+	if doneErr := done(); err == nil {
+		err = doneErr
+	}
+	return err
+}
+
+// headlessCommand runs a fixed number of frames as fast as possible, with
+// the null frontend
+func headlessCommand(args []string) error {
+	var machine machineFlags
+	var record recordFlags
+
+	fs := newFlagSet("headless")
+	machine.register(fs)
+	record.register(fs)
+	paletteName := paletteFlag(fs)
+	frames := fs.Int("frames", 600, "frames to run (60 per second)")
+	screenshot := fs.String("screenshot", "", "save the last frame as a PNG to this file")
+	fs.IntVar(&screenshotScale, "screenshot-scale", 8, "screenshot size as a multiple of 64x32")
+
+	entry := entryFlag(fs)
+	path, err := parseArgs(fs, args)
+	if err != nil {
+		return err
 	}
 
-	chip8 := cpu.NewChip8()
-	chip8.Init()
-	chip8.LoadRom(rom)
+	if *frames <= 0 {
+		return usageError("-frames must be positive")
+	}
 
-	sdl.Init(sdl.INIT_EVERYTHING)
-	defer sdl.Quit()
+	rom, err := loadRom(path, *entry)
+	if err != nil {
+		return err
+	}
+	detected(rom)
 
-	window, err := sdl.CreateWindow("Untitled", sdl.WINDOWPOS_CENTERED, sdl.WINDOWPOS_CENTERED, 1024, 512, sdl.WINDOW_SHOWN)
+	set := explicit(fs)
+	palette, err = parsePalette(*paletteName, set, rom)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	defer window.Destroy()
 
-	renderer, err := sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
+	chip8, done, err := machine.newChip8(rom, set)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	defer renderer.Destroy()
 
-	go timersThread(chip8)
+	if record.path != "" {
+		startRecording(record.path)
+	}
+
+	effects = &render.Effects{Scale: 1}
+	err = run(chip8, frontend.Null{}, render.NewPersistence(render.PERSISTENCE_OFF), false, *frames)
+	if recordErr := stopRecording(); err == nil {
+		err = recordErr
+	}
+
+	if err == nil && *screenshot != "" {
+		err = render.SavePNG(*screenshot, render.Screenshot(&chip8.Video, palette, screenshotScale))
+	}
+
+	if doneErr := done(); err == nil {
+		err = doneErr
+	}
+	return err
+}
+
+// run is the emulation loop shared by the frontends. It runs until the
+// frontend quits or limit frames ran, when limit isn't 0.
+func run(chip8 *cpu.Chip8, fe frontend.Frontend, persistence *render.Persistence, paced bool, limit int) error {
+	// Emulation frames are paced on their own, so the speed doesn't depend
+	// on the monitor refresh rate
+	frameInterval := time.Second / 60
+	lastFrame := time.Now()
+	frame := make([]byte, render.WIDTH*render.HEIGHT*4)
+	frames := 0
+
+	for !fe.Quit() && (limit == 0 || frames < limit) {
+		for _, action := range fe.Poll(chip8) {
+			hotkey(chip8, action)
+		}
+
+		due := 1
+		if paced {
+			now := time.Now()
+
+			// Don't try to catch up after the window was stalled
+			if now.Sub(lastFrame) > 5*frameInterval {
+				lastFrame = now.Add(-frameInterval)
+			}
 
-	for range ticker.C {
-		if !keepRunning {
-			os.Exit(1)
+			due = int(now.Sub(lastFrame) / frameInterval)
+			if due == 0 {
+				time.Sleep(time.Millisecond)
+				continue
+			}
+			lastFrame = lastFrame.Add(time.Duration(due) * frameInterval)
 		}
-		chip8.Cycle()
-		if chip8.DrawFlag() {
-			chip8.SetDrawFlag(false)
-			renderer.SetDrawColor(255, 0, 0, 255)
-			renderer.Clear()
-
-			for i, v := range chip8.Video {
-				if v != 0 {
-					renderer.SetDrawColor(255, 255, 255, 255)
-				} else {
-					renderer.SetDrawColor(0, 0, 0, 255)
-				}
-
-				renderer.FillRect(&sdl.Rect{
-					Y: int32(i/64) * 16,
-					X: int32(i%64) * 16,
-					W: 16,
-					H: 16,
-				})
+
+		draw := false
+		for i := 0; i < due && (limit == 0 || frames < limit); i++ {
+			changed := chip8.RunFrame()
+			if err := recordFrame(chip8, changed); err != nil {
+				return err
 			}
+			fe.Sound(chip8.SoundActive())
 
-			renderer.Present()
+			if changed || persistence.Active() {
+				persistence.Add(&chip8.Video, palette)
+				draw = true
+			}
+			frames++
+		}
+
+		if draw {
+			persistence.Fill(frame)
+			if err := fe.Present(effects.Apply(frame), palette); err != nil {
+				return fmt.Errorf("present frame: %w", err)
+			}
 		}
-		listenKeypad(chip8)
 	}
+
+	return nil
 }
 
-func listenKeypad(chip8 *cpu.Chip8) {
-	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-		switch et := event.(type) {
-		case *sdl.KeyboardEvent:
-			if et.Type == sdl.KEYDOWN || et.Type == sdl.KEYUP {
-				var ev uint8
-
-				if et.Type == sdl.KEYDOWN {
-					ev = 1
-				} else {
-					ev = 0
-				}
-
-				switch et.Keysym.Sym {
-				case sdl.K_1:
-					chip8.OnKeyEvent(0x1, ev)
-				case sdl.K_2:
-					chip8.OnKeyEvent(0x2, ev)
-				case sdl.K_3:
-					chip8.OnKeyEvent(0x3, ev)
-				case sdl.K_4:
-					chip8.OnKeyEvent(0xC, ev)
-				case sdl.K_q:
-					chip8.OnKeyEvent(0x4, ev)
-				case sdl.K_w:
-					chip8.OnKeyEvent(0x5, ev)
-				case sdl.K_e:
-					chip8.OnKeyEvent(0x6, ev)
-				case sdl.K_r:
-					chip8.OnKeyEvent(0xD, ev)
-				case sdl.K_a:
-					chip8.OnKeyEvent(0x7, ev)
-				case sdl.K_s:
-					chip8.OnKeyEvent(0x8, ev)
-				case sdl.K_d:
-					chip8.OnKeyEvent(0x9, ev)
-				case sdl.K_f:
-					chip8.OnKeyEvent(0xE, ev)
-				case sdl.K_z:
-					chip8.OnKeyEvent(0xA, ev)
-				case sdl.K_x:
-					chip8.OnKeyEvent(0x0, ev)
-				case sdl.K_c:
-					chip8.OnKeyEvent(0xB, ev)
-				case sdl.K_v:
-					chip8.OnKeyEvent(0xF, ev)
-				}
-			}
-		case *sdl.QuitEvent:
-			keepRunning = false
+// hotkey handles the emulator shortcuts the frontend passed on
+func hotkey(chip8 *cpu.Chip8, action frontend.Action) {
+	switch action {
+	case frontend.ACTION_NEXT_PALETTE:
+		palette = palette.Next()
+		status("Palette: " + palette.Name)
+	case frontend.ACTION_TOGGLE_FILTERS:
+		effects.Bypass = !effects.Bypass
+	case frontend.ACTION_TOGGLE_RECORDING:
+		if recorder == nil {
+			startRecording("")
+		} else if err := stopRecording(); err != nil {
+			// Keep playing, the recording can be started again
+			status(err.Error())
 		}
+		return
+	case frontend.ACTION_SCREENSHOT:
+		saveScreenshot(chip8)
+		return
+	default:
+		return
 	}
 
+	// Redraw with the new settings on the next frame
+	chip8.SetDrawFlag(true)
 }
 
-func timersThread(chip8 *cpu.Chip8) {
-	tickerTimers := time.NewTicker(time.Second / 60)
-	for range tickerTimers.C {
-		chip8.UpdateTimers()
+// saveScreenshot writes the display to <rom>-<time>.png in the working
+// directory
+func saveScreenshot(chip8 *cpu.Chip8) {
+	name := strings.TrimSuffix(romName, filepath.Ext(romName))
+	path := fmt.Sprintf("%s-%s.png", name, time.Now().Format("20060102-150405"))
+
+	if err := render.SavePNG(path, render.Screenshot(&chip8.Video, palette, screenshotScale)); err != nil {
+		status("Fail to save screenshot: " + err.Error())
+		return
 	}
+
+	status("Screenshot saved: " + path)
 }
@@ -0,0 +1,61 @@
+//go:build !js && !wasm
+// +build !js,!wasm
+
+package main
+
+import (
+	"fmt"
+	"github.com/brunocroh/chip8/cpu"
+	"github.com/brunocroh/chip8/render"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var recorder *render.Recorder
+var recordPath string
+var gifScale int
+var gifLimit time.Duration
+
+// startRecording records a GIF saved to path, or <rom>-<time>.gif when path
+// is empty
+func startRecording(path string) {
+	if path == "" {
+		name := strings.TrimSuffix(romName, filepath.Ext(romName))
+		path = fmt.Sprintf("%s-%s.gif", name, time.Now().Format("20060102-150405"))
+	}
+
+	recorder = render.NewRecorder(palette, gifScale, gifLimit)
+	recordPath = path
+	status("Recording: " + path)
+}
+
+// stopRecording saves the recording, if any
+func stopRecording() error {
+	if recorder == nil {
+		return nil
+	}
+
+	r := recorder
+	recorder = nil
+	if err := r.Save(recordPath); err != nil {
+		return fmt.Errorf("save recording: %w", err)
+	}
+
+	status(fmt.Sprintf("Recording saved: %s (%v)", recordPath, r.Duration().Round(10*time.Millisecond)))
+	return nil
+}
+
+// recordFrame captures the frame just run, stopping at the duration limit
+func recordFrame(chip8 *cpu.Chip8, changed bool) error {
+	if recorder == nil {
+		return nil
+	}
+
+	recorder.Tick(&chip8.Video, changed)
+
+	if recorder.Full() {
+		return stopRecording()
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package cpu
+
+// Coverage counts, per address, how often the CPU executed an instruction
+// there and read or wrote the byte. Only Fx33, Fx55, Fx65 and Dxyn access
+// memory besides instruction fetches.
+type Coverage struct {
+	Exec  [4096]uint32
+	Read  [4096]uint32
+	Write [4096]uint32
+}
+
+// SetCoverage records the memory accesses into cov from now on, nil turns
+// recording off.
+func (c *Chip8) SetCoverage(cov *Coverage) {
+	c.coverage = cov
+}
+
+func (c *Chip8) read(address uint16) uint8 {
+	if c.coverage != nil {
+		c.coverage.Read[address]++
+	}
+	return c.memory[address]
+}
+
+func (c *Chip8) write(address uint16, v uint8) {
+	if c.coverage != nil {
+		c.coverage.Write[address]++
+	}
+	c.memory[address] = v
+}
@@ -1,8 +1,11 @@
 package cpu
 
+import "math/rand"
+
 const START_ADDRESS = 0x200
 const FONTSET_START_ADDRESS = 0x50
 const FONTSET_SIZE = 80
+const DEFAULT_CYCLES_PER_FRAME = 8
 
 var fontset = [FONTSET_SIZE]byte{
 	0xF0, 0x90, 0x90, 0x90, 0xF0, // 0
@@ -37,6 +40,22 @@ type Chip8 struct {
 	instructions *instructions
 	drawFlag     bool // Draw flag
 
+	cyclesPerFrame int        // Instructions executed on each RunFrame
+	timing         TimingMode // How RunFrame paces instructions
+	cycleBudget    int        // Machine cycles left in the frame (TIMING_VIP)
+	quirks         Quirks     // Interpreter specific behaviours
+	waitVBlank     bool       // CPU stalled until the next UpdateTimers
+
+	cycles        uint64         // Cycles run since Reset
+	keyEvents     []KeyEvent     // Key events not yet applied to keypad
+	inputRecorder func(KeyEvent) // Called for every applied key event
+	waitingKey    bool           // Fx0A in progress
+	waitPressed   uint16         // Keys pressed since Fx0A started
+	waitReleased  uint8          // First of those keys released, or NO_KEY
+	rng           *rand.Rand     // Source for Cxkk, math/rand when nil
+	tracer        func(pc, opcode uint16)
+	coverage      *Coverage // Memory accesses, when recorded
+
 	Video [2048]uint32 // Display buffer
 }
 
@@ -54,7 +73,13 @@ func (c *Chip8) Reset() {
 	c.instructions = NewInstructions()
 	c.Video = [2048]uint32{} //64*32
 	c.drawFlag = false
-
+	c.cycleBudget = 0
+	c.waitVBlank = false
+	c.cycles = 0
+	c.keyEvents = nil
+	c.waitingKey = false
+	c.waitPressed = 0
+	c.waitReleased = NO_KEY
 }
 
 func NewChip8() *Chip8 {
@@ -73,6 +98,9 @@ func NewChip8() *Chip8 {
 
 		Video:    [2048]uint32{}, //64*32
 		drawFlag: false,
+
+		cyclesPerFrame: DEFAULT_CYCLES_PER_FRAME,
+		waitReleased:   NO_KEY,
 	}
 }
 
@@ -87,8 +115,14 @@ func (c *Chip8) Init() {
 }
 
 func (c *Chip8) LoadRom(rom []byte) {
-	for i, v := range rom {
-		c.memory[START_ADDRESS+i] = v
+	c.LoadRomAt(START_ADDRESS, rom)
+}
+
+// LoadRomAt copies rom to memory at address, dropping what doesn't fit.
+// utils.ReadRom refuses ROMs that don't.
+func (c *Chip8) LoadRomAt(address uint16, rom []byte) {
+	if int(address) < len(c.memory) {
+		copy(c.memory[address:], rom)
 	}
 }
 
@@ -97,13 +131,66 @@ func (c *Chip8) incrementCounter() {
 }
 
 func (c *Chip8) Cycle() {
+	c.processInput()
+	c.cycles++
+
+	if c.waitVBlank {
+		return
+	}
+
 	opcode := c.fetchOpcode()
+	if c.tracer != nil {
+		c.tracer(c.pc, opcode)
+	}
+	if c.coverage != nil {
+		c.coverage.Exec[c.pc]++
+	}
 	c.incrementCounter()
 	c.decodeExecute(opcode)
 }
 
-func (c *Chip8) OnKeyEvent(key uint8, press uint8) {
-	c.keypad[key] = press
+// RunFrame executes one 60 Hz frame followed by a single timer tick. It
+// returns true when the display changed in the frame.
+//
+// With TIMING_FIXED the frame is cyclesPerFrame instructions, with TIMING_VIP
+// it lasts as many instructions as fit in the VIP machine-cycle budget.
+func (c *Chip8) RunFrame() bool {
+	switch c.timing {
+	case TIMING_VIP:
+		c.runVipFrame()
+	default:
+		for i := 0; i < c.cyclesPerFrame && !c.waitVBlank; i++ {
+			c.Cycle()
+		}
+	}
+	c.UpdateTimers()
+
+	draw := c.drawFlag
+	c.drawFlag = false
+	return draw
+}
+
+func (c *Chip8) CyclesPerFrame() int {
+	return c.cyclesPerFrame
+}
+
+func (c *Chip8) SetCyclesPerFrame(n int) {
+	if n < 1 {
+		n = 1
+	}
+	c.cyclesPerFrame = n
+}
+
+// Seed makes Cxkk deterministic, which together with recorded key events
+// allows a session to be replayed exactly.
+func (c *Chip8) Seed(seed int64) {
+	c.rng = rand.New(rand.NewSource(seed))
+}
+
+// SetTracer calls trace with the address and opcode of every instruction
+// before it runs, nil turns tracing off.
+func (c *Chip8) SetTracer(trace func(pc, opcode uint16)) {
+	c.tracer = trace
 }
 
 func (c *Chip8) DrawFlag() bool {
@@ -0,0 +1,129 @@
+package cpu
+
+import "testing"
+
+func TestRunFrame(t *testing.T) {
+	chip8 := NewChip8()
+	chip8.Init()
+	chip8.SetCyclesPerFrame(3)
+	chip8.SetDrawFlag(false)
+
+	// 6005 (V0 = 5), 7001 (V0 += 1), F015 (DT = V0), 7001
+	chip8.LoadRom([]byte{0x60, 0x05, 0x70, 0x01, 0xF0, 0x15, 0x70, 0x01})
+
+	if chip8.RunFrame() {
+		t.Errorf("Expected no display change")
+	}
+
+	if chip8.pc != START_ADDRESS+6 {
+		t.Errorf("Expected 3 instructions executed, pc = %#x", chip8.pc)
+	}
+
+	if chip8.delayTimer != 5 {
+		t.Errorf("Expected delay timer ticked once, got %d", chip8.delayTimer)
+	}
+}
+
+func TestRunFrameVipDrawEndsFrame(t *testing.T) {
+	chip8 := NewChip8()
+	chip8.Init()
+	chip8.SetTimingMode(TIMING_VIP)
+
+	// 6001 (V0 = 1), D001 (draw 1 row), 7001 (V0 += 1)
+	chip8.LoadRom([]byte{0x60, 0x01, 0xD0, 0x01, 0x70, 0x01})
+
+	chip8.RunFrame()
+
+	if chip8.pc != START_ADDRESS+4 {
+		t.Errorf("Expected frame to end after draw, pc = %#x", chip8.pc)
+	}
+
+	if chip8.cycleBudget >= 0 {
+		t.Errorf("Expected draw cost carried into the next frame, budget = %d", chip8.cycleBudget)
+	}
+}
+
+func TestVBlankWait(t *testing.T) {
+	chip8 := NewChip8()
+	chip8.Init()
+	chip8.SetQuirks(Quirks{VBlankWait: true})
+
+	// D001 (draw 1 row), 7001 (V0 += 1)
+	chip8.LoadRom([]byte{0xD0, 0x01, 0x70, 0x01})
+
+	chip8.Cycle()
+	chip8.Cycle()
+
+	if chip8.register[0] != 0 {
+		t.Errorf("Expected CPU stalled after draw")
+	}
+
+	chip8.UpdateTimers()
+	chip8.Cycle()
+
+	if chip8.register[0] != 1 {
+		t.Errorf("Expected CPU resumed after timer tick")
+	}
+}
+
+func TestKeyEventsReplay(t *testing.T) {
+	// 6000 (V0 = 0), E09E (skip if V0 pressed), 1202, 7101 (V1 += 1), 1202
+	rom := []byte{0x60, 0x00, 0xE0, 0x9E, 0x12, 0x02, 0x71, 0x01, 0x12, 0x02}
+
+	var recorded []KeyEvent
+	chip8 := NewChip8()
+	chip8.Init()
+	chip8.LoadRom(rom)
+	chip8.SetInputRecorder(func(e KeyEvent) {
+		recorded = append(recorded, e)
+	})
+
+	chip8.QueueKeyEvent(KeyEvent{Key: 0x0, Pressed: true, Cycle: 7})
+	chip8.QueueKeyEvent(KeyEvent{Key: 0x0, Pressed: false, Cycle: 3})
+	chip8.QueueKeyEvent(KeyEvent{Key: 0x0, Pressed: false, Cycle: 7})
+	for i := 0; i < 20; i++ {
+		chip8.Cycle()
+	}
+
+	if len(recorded) != 3 || recorded[1].Cycle != 7 || recorded[2].Cycle != 8 {
+		t.Fatalf("Unexpected recorded events %v", recorded)
+	}
+
+	replay := NewChip8()
+	replay.Init()
+	replay.LoadRom(rom)
+	for _, e := range recorded {
+		replay.QueueKeyEvent(e)
+	}
+	for i := 0; i < 20; i++ {
+		replay.Cycle()
+	}
+
+	if replay.register[1] != chip8.register[1] || replay.pc != chip8.pc {
+		t.Errorf("Replay diverged: V1 %d != %d", replay.register[1], chip8.register[1])
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	// A300 (I = 300), F155 (store V0-V1), A300, D011 (draw a row), 1208
+	rom := []byte{0xA3, 0x00, 0xF1, 0x55, 0xA3, 0x00, 0xD0, 0x11, 0x12, 0x08}
+
+	var cov Coverage
+	chip8 := NewChip8()
+	chip8.Init()
+	chip8.LoadRom(rom)
+	chip8.SetCoverage(&cov)
+	for i := 0; i < 6; i++ {
+		chip8.Cycle()
+	}
+
+	if cov.Exec[0x200] != 1 || cov.Exec[0x208] != 2 || cov.Exec[0x201] != 0 {
+		t.Errorf("Unexpected exec counts %d %d %d", cov.Exec[0x200], cov.Exec[0x208], cov.Exec[0x201])
+	}
+	if cov.Write[0x300] != 1 || cov.Write[0x301] != 1 || cov.Write[0x302] != 0 {
+		t.Errorf("Expected writes at 300 and 301, got %v", cov.Write[0x300:0x303])
+	}
+	if cov.Read[0x300] != 1 || cov.Read[0x301] != 0 {
+		t.Errorf("Expected a read at 300, got %v", cov.Read[0x300:0x302])
+	}
+}
@@ -48,11 +48,11 @@ func (c *Chip8) decodeExecute(opcode uint16) {
 		case 0x5:
 			c.instructions.subVxVy(c, x, y)
 		case 0x6:
-			c.instructions.shrVx(c, x)
+			c.instructions.shrVx(c, x, y)
 		case 0x7:
 			c.instructions.subnVxVy(c, x, y)
 		case 0xE:
-			c.instructions.shlVx(c, x)
+			c.instructions.shlVx(c, x, y)
 		}
 	case 0x9000:
 		c.instructions.sneVxVy(c, x, y)
@@ -77,7 +77,7 @@ func (c *Chip8) decodeExecute(opcode uint16) {
 		case 0x0007:
 			c.instructions.ldVxDt(c, x)
 		case 0x000A:
-			c.instructions.ldVxK(c)
+			c.instructions.ldVxK(c, x)
 		case 0x0015:
 			c.instructions.ldDtVx(c, x)
 		case 0x0018:
@@ -0,0 +1,119 @@
+package cpu
+
+import "fmt"
+
+// Disassemble returns the mnemonic of opcode, in the syntax of Cowgod's
+// CHIP-8 technical reference. Words that aren't instructions, usually
+// sprite data, are returned as DW.
+func Disassemble(opcode uint16) string {
+	nnn := opcode & 0x0FFF
+	kk := opcode & 0x00FF
+	x := (opcode & 0x0F00) >> 8
+	y := (opcode & 0x00F0) >> 4
+	n := opcode & 0x000F
+
+	switch opcode & 0xF000 {
+	case 0x0000:
+		switch opcode {
+		case 0x00E0:
+			return "CLS"
+		case 0x00EE:
+			return "RET"
+		}
+		return fmt.Sprintf("SYS 0x%03X", nnn)
+	case 0x1000:
+		return fmt.Sprintf("JP 0x%03X", nnn)
+	case 0x2000:
+		return fmt.Sprintf("CALL 0x%03X", nnn)
+	case 0x3000:
+		return fmt.Sprintf("SE V%X, 0x%02X", x, kk)
+	case 0x4000:
+		return fmt.Sprintf("SNE V%X, 0x%02X", x, kk)
+	case 0x5000:
+		if n == 0 {
+			return fmt.Sprintf("SE V%X, V%X", x, y)
+		}
+	case 0x6000:
+		return fmt.Sprintf("LD V%X, 0x%02X", x, kk)
+	case 0x7000:
+		return fmt.Sprintf("ADD V%X, 0x%02X", x, kk)
+	case 0x8000:
+		if mnemonic, ok := aluMnemonics[n]; ok {
+			return fmt.Sprintf("%s V%X, V%X", mnemonic, x, y)
+		}
+	case 0x9000:
+		if n == 0 {
+			return fmt.Sprintf("SNE V%X, V%X", x, y)
+		}
+	case 0xA000:
+		return fmt.Sprintf("LD I, 0x%03X", nnn)
+	case 0xB000:
+		return fmt.Sprintf("JP V0, 0x%03X", nnn)
+	case 0xC000:
+		return fmt.Sprintf("RND V%X, 0x%02X", x, kk)
+	case 0xD000:
+		return fmt.Sprintf("DRW V%X, V%X, %d", x, y, n)
+	case 0xE000:
+		switch kk {
+		case 0x9E:
+			return fmt.Sprintf("SKP V%X", x)
+		case 0xA1:
+			return fmt.Sprintf("SKNP V%X", x)
+		}
+	case 0xF000:
+		if format, ok := miscFormats[kk]; ok {
+			return fmt.Sprintf(format, x)
+		}
+	}
+
+	return fmt.Sprintf("DW 0x%04X", opcode)
+}
+
+var aluMnemonics = map[uint16]string{
+	0x0: "LD",
+	0x1: "OR",
+	0x2: "AND",
+	0x3: "XOR",
+	0x4: "ADD",
+	0x5: "SUB",
+	0x6: "SHR",
+	0x7: "SUBN",
+	0xE: "SHL",
+}
+
+var miscFormats = map[uint16]string{
+	0x07: "LD V%X, DT",
+	0x0A: "LD V%X, K",
+	0x15: "LD DT, V%X",
+	0x18: "LD ST, V%X",
+	0x1E: "ADD I, V%X",
+	0x29: "LD F, V%X",
+	0x33: "LD B, V%X",
+	0x55: "LD [I], V%X",
+	0x65: "LD V%X, [I]",
+}
+
+// Line is one word of a disassembled ROM.
+type Line struct {
+	Address uint16
+	Opcode  uint16
+	Text    string
+}
+
+// DisassembleRom decodes rom two bytes at a time, as loaded at address. A
+// trailing odd byte is returned as DB.
+func DisassembleRom(rom []byte, address uint16) []Line {
+	lines := make([]Line, 0, len(rom)/2+1)
+
+	for i := 0; i+1 < len(rom); i += 2 {
+		opcode := uint16(rom[i])<<8 | uint16(rom[i+1])
+		lines = append(lines, Line{address + uint16(i), opcode, Disassemble(opcode)})
+	}
+
+	if len(rom)%2 == 1 {
+		last := rom[len(rom)-1]
+		lines = append(lines, Line{address + uint16(len(rom)-1), uint16(last), fmt.Sprintf("DB 0x%02X", last)})
+	}
+
+	return lines
+}
@@ -0,0 +1,35 @@
+package cpu
+
+import "testing"
+
+func TestDisassemble(t *testing.T) {
+	tests := map[uint16]string{
+		0x00E0: "CLS",
+		0x00EE: "RET",
+		0x0123: "SYS 0x123",
+		0x1A2B: "JP 0xA2B",
+		0x3C0F: "SE VC, 0x0F",
+		0x5120: "SE V1, V2",
+		0x5121: "DW 0x5121",
+		0x812E: "SHL V1, V2",
+		0x8128: "DW 0x8128",
+		0xD015: "DRW V0, V1, 5",
+		0xE59E: "SKP V5",
+		0xF265: "LD V2, [I]",
+		0xF0FF: "DW 0xF0FF",
+	}
+
+	for opcode, want := range tests {
+		if got := Disassemble(opcode); got != want {
+			t.Errorf("Disassemble(%04X) = %q, want %q", opcode, got, want)
+		}
+	}
+}
+
+func TestDisassembleRom(t *testing.T) {
+	lines := DisassembleRom([]byte{0x00, 0xE0, 0x12}, START_ADDRESS)
+
+	if len(lines) != 2 || lines[1].Address != START_ADDRESS+2 || lines[1].Text != "DB 0x12" {
+		t.Errorf("DisassembleRom = %+v", lines)
+	}
+}
@@ -0,0 +1,93 @@
+package cpu
+
+import "sort"
+
+const NO_KEY = 0xFF
+
+// KeyEvent is a keypad change applied at the start of cycle number Cycle
+// (see CycleCount), or at the first cycle boundary after it.
+type KeyEvent struct {
+	Key     uint8
+	Pressed bool
+	Cycle   uint64
+}
+
+// OnKeyEvent queues a keypad change for the next Cycle.
+func (c *Chip8) OnKeyEvent(key uint8, press uint8) {
+	c.QueueKeyEvent(KeyEvent{Key: key, Pressed: press == 1, Cycle: c.cycles})
+}
+
+// QueueKeyEvent queues e, keeping the queue ordered by Cycle. Events with the
+// same Cycle are applied in the order they were queued.
+func (c *Chip8) QueueKeyEvent(e KeyEvent) {
+	e.Key &= 0xF
+
+	i := sort.Search(len(c.keyEvents), func(i int) bool {
+		return c.keyEvents[i].Cycle > e.Cycle
+	})
+
+	c.keyEvents = append(c.keyEvents, KeyEvent{})
+	copy(c.keyEvents[i+1:], c.keyEvents[i:])
+	c.keyEvents[i] = e
+}
+
+// PendingKeyEvents returns the number of queued events not yet applied.
+func (c *Chip8) PendingKeyEvents() int {
+	return len(c.keyEvents)
+}
+
+// CycleCount returns the number of cycles run since the last Reset.
+func (c *Chip8) CycleCount() uint64 {
+	return c.cycles
+}
+
+// SetInputRecorder registers fn to be called with every key event as it is
+// applied, stamped with the cycle it was applied at. Queuing the recorded
+// events again on a fresh Chip8 reproduces the same input.
+func (c *Chip8) SetInputRecorder(fn func(KeyEvent)) {
+	c.inputRecorder = fn
+}
+
+// processInput applies queued key events that are due to the keypad. It stops
+// before an event that would undo one already applied in the same call, so a
+// tap shorter than a cycle is still seen by at least one instruction.
+func (c *Chip8) processInput() {
+	var touched uint16
+
+	n := 0
+	for _, e := range c.keyEvents {
+		bit := uint16(1) << e.Key
+		if e.Cycle > c.cycles || touched&bit != 0 {
+			break
+		}
+		touched |= bit
+
+		e.Cycle = c.cycles
+		c.applyKeyEvent(e)
+		n++
+	}
+
+	c.keyEvents = append(c.keyEvents[:0], c.keyEvents[n:]...)
+}
+
+func (c *Chip8) applyKeyEvent(e KeyEvent) {
+	bit := uint16(1) << e.Key
+
+	if c.waitingKey {
+		if e.Pressed {
+			c.waitPressed |= bit
+		} else if c.waitPressed&bit != 0 && c.waitReleased == NO_KEY {
+			c.waitReleased = e.Key
+		}
+	}
+
+	if e.Pressed {
+		c.keypad[e.Key] = 1
+	} else {
+		c.keypad[e.Key] = 0
+	}
+
+	if c.inputRecorder != nil {
+		c.inputRecorder(e)
+	}
+}
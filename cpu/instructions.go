@@ -140,9 +140,14 @@ func (m *instructions) loadVyIntoVx(c *Chip8, x uint16, y uint16) {
 Set Vx = Vx OR Vy.
 
 Performs a bitwise OR on the values of Vx and Vy, then stores the result in Vx. A bitwise OR compares the corrseponding bits from two values, and if either bit is 1, then the same bit in the result is also 1. Otherwise, it is 0.
+
+With the VFReset quirk VF is set to 0.
 */
 func (m *instructions) orVxVy(c *Chip8, x uint16, y uint16) {
 	c.register[x] = c.register[x] | c.register[y]
+	if c.quirks.VFReset {
+		c.register[0xF] = 0
+	}
 }
 
 /*
@@ -151,9 +156,14 @@ func (m *instructions) orVxVy(c *Chip8, x uint16, y uint16) {
 Set Vx = Vx AND Vy.
 
 Performs a bitwise AND on the values of Vx and Vy, then stores the result in Vx. A bitwise AND compares the corrseponding bits from two values, and if both bits are 1, then the same bit in the result is also 1. Otherwise, it is 0.
+
+With the VFReset quirk VF is set to 0.
 */
 func (m *instructions) andVxVy(c *Chip8, x uint16, y uint16) {
 	c.register[x] = c.register[x] & c.register[y]
+	if c.quirks.VFReset {
+		c.register[0xF] = 0
+	}
 }
 
 /*
@@ -162,9 +172,14 @@ func (m *instructions) andVxVy(c *Chip8, x uint16, y uint16) {
 Set Vx = Vx XOR Vy.
 
 Performs a bitwise exclusive OR on the values of Vx and Vy, then stores the result in Vx. An exclusive OR compares the corrseponding bits from two values, and if the bits are not both the same, then the corresponding bit in the result is set to 1. Otherwise, it is 0.
+
+With the VFReset quirk VF is set to 0.
 */
 func (m *instructions) xorVxVy(c *Chip8, x uint16, y uint16) {
 	c.register[x] = c.register[x] ^ c.register[y]
+	if c.quirks.VFReset {
+		c.register[0xF] = 0
+	}
 }
 
 /*
@@ -208,8 +223,13 @@ func (m *instructions) subVxVy(c *Chip8, x uint16, y uint16) {
 Set Vx = Vx SHR 1.
 
 If the least-significant bit of Vx is 1, then VF is set to 1, otherwise 0. Then Vx is divided by 2.
+
+With the ShiftVy quirk Vy is shifted and stored in Vx.
 */
-func (m *instructions) shrVx(c *Chip8, x uint16) {
+func (m *instructions) shrVx(c *Chip8, x uint16, y uint16) {
+	if c.quirks.ShiftVy {
+		c.register[x] = c.register[y]
+	}
 	bit := c.register[x]
 
 	c.register[x] = c.register[x] >> 1
@@ -243,12 +263,17 @@ func (m *instructions) subnVxVy(c *Chip8, x uint16, y uint16) {
 Set Vx = Vx SHL 1.
 
 If the most-significant bit of Vx is 1, then VF is set to 1, otherwise to 0. Then Vx is multiplied by 2.
+
+With the ShiftVy quirk Vy is shifted and stored in Vx.
 */
-func (m *instructions) shlVx(c *Chip8, x uint16) {
+func (m *instructions) shlVx(c *Chip8, x uint16, y uint16) {
+	if c.quirks.ShiftVy {
+		c.register[x] = c.register[y]
+	}
 	bit := c.register[x]
 
 	c.register[x] = c.register[x] << 1
-	if bit&0x01 == 1 {
+	if bit&0x80 != 0 {
 		c.register[0xF] = 1
 	} else {
 		c.register[0xF] = 0
@@ -285,8 +310,14 @@ Bnnn - JP V0, addr
 Jump to location nnn + V0.
 
 The program counter is set to nnn plus the value of V0.
+
+With the JumpVx quirk it is Bxnn, jumping to xnn plus the value of Vx.
 */
 func (m *instructions) jumpV0(c *Chip8, nnn uint16) {
+	if c.quirks.JumpVx {
+		c.pc = nnn + uint16(c.register[nnn>>8])
+		return
+	}
 	c.pc = nnn + uint16(c.register[0])
 }
 
@@ -298,7 +329,12 @@ Set Vx = random byte AND kk.
 The interpreter generates a random number from 0 to 255, which is then ANDed with the value kk. The results are stored in Vx. See instruction 8xy2 for more information on AND.
 */
 func (m *instructions) randonVxKk(c *Chip8, x uint16, kk uint8) {
-	random := rand.Intn(255)
+	var random int
+	if c.rng != nil {
+		random = c.rng.Intn(256)
+	} else {
+		random = rand.Intn(256)
+	}
 	c.register[x] = uint8(random) & kk
 }
 
@@ -308,15 +344,29 @@ Dxyn - DRW Vx, Vy, nibble
 Display n-byte sprite starting at memory location I at (Vx, Vy), set VF = collision.
 
 The interpreter reads n bytes from memory, starting at the address stored in I. These bytes are then displayed as sprites on screen at coordinates (Vx, Vy). Sprites are XORed onto the existing screen. If this causes any pixels to be erased, VF is set to 1, otherwise it is set to 0. If the sprite is positioned so part of it is outside the coordinates of the display, it wraps around to the opposite side of the screen. See instruction 8xy3 for more information on XOR, and section 2.4, Display, for more information on the Chip-8 screen and sprites.
+
+With the Clip quirk only the starting position wraps, the parts of the sprite past the edges are not drawn.
 */
 func (m *instructions) draw(c *Chip8, x uint16, y uint16, n uint16) {
 	vx := uint16(c.register[x])
 	vy := uint16(c.register[y])
 	c.register[0xF] = 0
 
+	if c.quirks.Clip {
+		vx, vy = vx%64, vy%32
+	}
+
 	for yLine := uint16(0); yLine < n; yLine++ {
-		pixel := c.memory[c.index+yLine]
+		if c.quirks.Clip && vy+yLine >= 32 {
+			break
+		}
+
+		pixel := c.read(c.index + yLine)
 		for xLine := uint16(0); xLine < 8; xLine++ {
+			if c.quirks.Clip && vx+xLine >= 64 {
+				break
+			}
+
 			if (pixel & (0x80 >> xLine)) != 0 {
 				xPos := (vx + xLine) % 64
 				yPos := (vy + yLine) % 32
@@ -329,6 +379,10 @@ func (m *instructions) draw(c *Chip8, x uint16, y uint16, n uint16) {
 		}
 	}
 	c.SetDrawFlag(true)
+
+	if c.quirks.VBlankWait {
+		c.waitVBlank = true
+	}
 }
 
 /*
@@ -374,19 +428,30 @@ Fx0A - LD Vx, K
 Wait for a key press, store the value of the key in Vx.
 
 All execution stops until a key is pressed, then the value of that key is stored in Vx.
+
+Like the COSMAC VIP, the value is stored once the key is released again.
 */
-func (m *instructions) ldVxK(c *Chip8) {
-	keyFound := false
-	for _, v := range c.keypad {
-		if v == 1 {
-			keyFound = true
-			break
+func (m *instructions) ldVxK(c *Chip8, x uint16) {
+	if !c.waitingKey {
+		c.waitingKey = true
+		c.waitPressed = 0
+		c.waitReleased = NO_KEY
+
+		// Keys already held count as pressed, they only need to be released
+		for i, v := range c.keypad {
+			if v == 1 {
+				c.waitPressed |= 1 << i
+			}
 		}
 	}
 
-	if !keyFound {
+	if c.waitReleased == NO_KEY {
 		c.pc -= 2
+		return
 	}
+
+	c.register[x] = c.waitReleased
+	c.waitingKey = false
 }
 
 /*
@@ -442,9 +507,9 @@ The interpreter takes the decimal value of Vx, and places the hundreds digit in
 */
 func (m *instructions) ldBVx(c *Chip8, x uint16) {
 	number := c.register[x]
-	c.memory[c.index] = number / 100
-	c.memory[c.index+1] = (number % 100) / 10
-	c.memory[c.index+2] = (number % 100) % 10
+	c.write(c.index, number/100)
+	c.write(c.index+1, (number%100)/10)
+	c.write(c.index+2, (number%100)%10)
 }
 
 /*
@@ -452,13 +517,15 @@ Fx55 - LD [I], Vx
 
 Store registers V0 through Vx in memory starting at location I.
 
-The interpreter copies the values of registers V0 through Vx into memory, starting at the address in I.
+The interpreter copies the values of registers V0 through Vx into memory, starting at the address in I. I is then set to I + x + 1, unless the KeepIndex quirk is set.
 */
 func (m *instructions) ldIndexVX(c *Chip8, x uint16) {
 	for i := uint16(0); i <= x; i++ {
-		c.memory[c.index+i] = c.register[i]
+		c.write(c.index+i, c.register[i])
+	}
+	if !c.quirks.KeepIndex {
+		c.index += x + 1
 	}
-	c.index += x + 1
 }
 
 /*
@@ -466,11 +533,13 @@ Fx65 - LD Vx, [I]
 
 Read registers V0 through Vx from memory starting at location I.
 
-The interpreter reads values from memory starting at location I into registers V0 through Vx.
+The interpreter reads values from memory starting at location I into registers V0 through Vx. I is then set to I + x + 1, unless the KeepIndex quirk is set.
 */
 func (m *instructions) ldVxIndex(c *Chip8, x uint16) {
 	for i := uint16(0); i <= x; i++ {
-		c.memory[c.index+i] = c.register[i]
+		c.register[i] = c.read(c.index + i)
+	}
+	if !c.quirks.KeepIndex {
+		c.index += x + 1
 	}
-	c.index += x + 1
 }
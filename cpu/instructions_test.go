@@ -22,3 +22,185 @@ func TestCls(t *testing.T) {
 		}
 	}
 }
+
+func TestLdVxKWaitsForRelease(t *testing.T) {
+	chip8 := NewChip8()
+	chip8.Init()
+
+	// F30A (V3 = K)
+	chip8.LoadRom([]byte{0xF3, 0x0A})
+
+	chip8.Cycle()
+	chip8.OnKeyEvent(0x7, 1)
+	chip8.Cycle()
+
+	if chip8.pc != START_ADDRESS {
+		t.Errorf("Expected to keep waiting while key is held")
+	}
+
+	chip8.OnKeyEvent(0x7, 0)
+	chip8.Cycle()
+
+	if chip8.pc != START_ADDRESS+2 || chip8.register[3] != 0x7 {
+		t.Errorf("Expected V3 = 7 after release, got V3 = %d, pc = %#x", chip8.register[3], chip8.pc)
+	}
+}
+
+func TestShortTapIsNotLost(t *testing.T) {
+	chip8 := NewChip8()
+	chip8.Init()
+	chip8.register[0] = 0x5
+
+	// E09E (skip if V0 pressed)
+	chip8.LoadRom([]byte{0xE0, 0x9E})
+
+	chip8.OnKeyEvent(0x5, 1)
+	chip8.OnKeyEvent(0x5, 0)
+	chip8.Cycle()
+
+	if chip8.pc != START_ADDRESS+4 {
+		t.Errorf("Expected tap between cycles to be seen by Ex9E")
+	}
+
+	if chip8.keypad[0x5] != 1 || len(chip8.keyEvents) != 1 {
+		t.Errorf("Expected release to stay queued for the next cycle")
+	}
+}
+
+func TestShlCarry(t *testing.T) {
+	chip8 := NewChip8()
+	ins := NewInstructions()
+
+	chip8.register[1] = 0x81
+	ins.shlVx(chip8, 1, 1)
+	if chip8.register[1] != 0x02 || chip8.register[0xF] != 1 {
+		t.Errorf("SHL 0x81 = %#x, VF = %d, want 0x2, 1", chip8.register[1], chip8.register[0xF])
+	}
+
+	chip8.register[1] = 0x01
+	ins.shlVx(chip8, 1, 1)
+	if chip8.register[1] != 0x02 || chip8.register[0xF] != 0 {
+		t.Errorf("SHL 0x01 = %#x, VF = %d, want 0x2, 0", chip8.register[1], chip8.register[0xF])
+	}
+}
+
+func TestLoadRegisters(t *testing.T) {
+	chip8 := NewChip8()
+	ins := NewInstructions()
+
+	chip8.index = 0x300
+	chip8.memory[0x300], chip8.memory[0x301] = 0xAA, 0xBB
+	ins.ldVxIndex(chip8, 1)
+	if chip8.register[0] != 0xAA || chip8.register[1] != 0xBB || chip8.index != 0x302 {
+		t.Errorf("LD V1, [I] = %#x %#x, I = %#x", chip8.register[0], chip8.register[1], chip8.index)
+	}
+	if chip8.memory[0x300] != 0xAA || chip8.memory[0x301] != 0xBB {
+		t.Errorf("Expected memory unchanged, got %#x %#x", chip8.memory[0x300], chip8.memory[0x301])
+	}
+}
+
+func TestShiftQuirk(t *testing.T) {
+	chip8 := NewChip8()
+	ins := NewInstructions()
+
+	chip8.register[1] = 0x01
+	chip8.register[2] = 0x81
+	ins.shlVx(chip8, 1, 2)
+	if chip8.register[1] != 0x02 || chip8.register[0xF] != 0 {
+		t.Errorf("SHL V1 = %#x, VF = %d, want 0x2, 0", chip8.register[1], chip8.register[0xF])
+	}
+
+	chip8.SetQuirks(Quirks{ShiftVy: true})
+	ins.shlVx(chip8, 1, 2)
+	if chip8.register[1] != 0x02 || chip8.register[0xF] != 1 {
+		t.Errorf("SHL V1, V2 = %#x, VF = %d, want 0x2, 1", chip8.register[1], chip8.register[0xF])
+	}
+
+	chip8.register[2] = 0x03
+	ins.shrVx(chip8, 1, 2)
+	if chip8.register[1] != 0x01 || chip8.register[0xF] != 1 {
+		t.Errorf("SHR V1, V2 = %#x, VF = %d, want 0x1, 1", chip8.register[1], chip8.register[0xF])
+	}
+}
+
+func TestKeepIndexQuirk(t *testing.T) {
+	chip8 := NewChip8()
+	ins := NewInstructions()
+
+	chip8.SetQuirks(Quirks{KeepIndex: true})
+	chip8.index = 0x300
+	chip8.register[0], chip8.register[1] = 0xAA, 0xBB
+
+	ins.ldIndexVX(chip8, 1)
+	if chip8.index != 0x300 || chip8.memory[0x301] != 0xBB {
+		t.Errorf("LD [I], V1 with KeepIndex: I = %#x, [I+1] = %#x", chip8.index, chip8.memory[0x301])
+	}
+
+	ins.ldVxIndex(chip8, 1)
+	if chip8.index != 0x300 || chip8.register[1] != 0xBB {
+		t.Errorf("LD V1, [I] with KeepIndex: I = %#x, V1 = %#x", chip8.index, chip8.register[1])
+	}
+}
+
+func TestJumpQuirk(t *testing.T) {
+	chip8 := NewChip8()
+	ins := NewInstructions()
+
+	chip8.register[0], chip8.register[3] = 0x10, 0x20
+	ins.jumpV0(chip8, 0x340)
+	if chip8.pc != 0x350 {
+		t.Errorf("JP V0, 0x340 = %#x, want 0x350", chip8.pc)
+	}
+
+	chip8.SetQuirks(Quirks{JumpVx: true})
+	ins.jumpV0(chip8, 0x340)
+	if chip8.pc != 0x360 {
+		t.Errorf("JP V3, 0x340 with JumpVx = %#x, want 0x360", chip8.pc)
+	}
+}
+
+func TestVFResetQuirk(t *testing.T) {
+	chip8 := NewChip8()
+	ins := NewInstructions()
+
+	chip8.register[0xF] = 1
+	ins.orVxVy(chip8, 1, 2)
+	if chip8.register[0xF] != 1 {
+		t.Errorf("Expected OR to leave VF alone, got %d", chip8.register[0xF])
+	}
+
+	chip8.SetQuirks(Quirks{VFReset: true})
+	for _, logic := range []func(*Chip8, uint16, uint16){ins.orVxVy, ins.andVxVy, ins.xorVxVy} {
+		chip8.register[0xF] = 1
+		logic(chip8, 1, 2)
+		if chip8.register[0xF] != 0 {
+			t.Errorf("Expected VF reset with VFReset, got %d", chip8.register[0xF])
+		}
+	}
+}
+
+func TestClipQuirk(t *testing.T) {
+	chip8 := NewChip8()
+	ins := NewInstructions()
+
+	// Two full rows at (60, 31), past the right and bottom edges
+	chip8.index = 0x300
+	chip8.memory[0x300], chip8.memory[0x301] = 0xFF, 0xFF
+	chip8.register[0], chip8.register[1] = 60, 31
+
+	ins.draw(chip8, 0, 1, 2)
+	if chip8.Video[31*64+60] != 1 || chip8.Video[31*64+2] != 1 || chip8.Video[2] != 1 {
+		t.Errorf("Expected the sprite to wrap around both edges")
+	}
+
+	chip8.Video = [2048]uint32{}
+	chip8.SetQuirks(Quirks{Clip: true})
+	chip8.register[0] = 124
+	ins.draw(chip8, 0, 1, 2)
+	if chip8.Video[31*64+60] != 1 || chip8.Video[31*64+63] != 1 {
+		t.Errorf("Expected the start position to wrap with Clip")
+	}
+	if chip8.Video[31*64+2] != 0 || chip8.Video[2] != 0 {
+		t.Errorf("Expected the sprite to be cut at the edges with Clip")
+	}
+}
@@ -0,0 +1,74 @@
+package cpu
+
+/*
+Quirks toggles behaviours that differ between CHIP-8 interpreters. The zero
+value is the behaviour most modern ROMs expect, see Platform for the
+presets of the historical interpreters.
+*/
+type Quirks struct {
+	ShiftVy    bool // 8xy6/8xyE shift Vy into Vx instead of shifting Vx (COSMAC VIP)
+	KeepIndex  bool // Fx55/Fx65 leave I unchanged instead of adding x+1 (SCHIP)
+	JumpVx     bool // Bxnn jumps to xnn + Vx instead of nnn + V0 (SCHIP)
+	VFReset    bool // 8xy1/8xy2/8xy3 set VF to 0 (COSMAC VIP)
+	Clip       bool // Sprites are cut at the screen edges instead of wrapping
+	VBlankWait bool // Dxyn stalls the CPU until the next timer tick
+}
+
+func (c *Chip8) Quirks() Quirks {
+	return c.quirks
+}
+
+func (c *Chip8) SetQuirks(q Quirks) {
+	c.quirks = q
+	if !q.VBlankWait {
+		c.waitVBlank = false
+	}
+}
+
+// WaitingVBlank reports whether the CPU is stalled until the next timer tick.
+func (c *Chip8) WaitingVBlank() bool {
+	return c.waitVBlank
+}
+
+type Platform uint8
+
+const (
+	PLATFORM_CHIP8  Platform = iota // Modern CHIP-8, the zero Quirks
+	PLATFORM_VIP                    // The original COSMAC VIP interpreter
+	PLATFORM_SCHIP                  // SUPER-CHIP 1.1 on the HP 48
+	PLATFORM_XOCHIP                 // XO-CHIP as implemented by Octo
+)
+
+var platformNames = map[Platform]string{
+	PLATFORM_CHIP8:  "chip8",
+	PLATFORM_VIP:    "vip",
+	PLATFORM_SCHIP:  "schip",
+	PLATFORM_XOCHIP: "xochip",
+}
+
+func ParsePlatform(name string) (Platform, bool) {
+	for p, n := range platformNames {
+		if n == name {
+			return p, true
+		}
+	}
+	return PLATFORM_CHIP8, false
+}
+
+func (p Platform) String() string {
+	return platformNames[p]
+}
+
+// Quirks returns the behaviours of the platform. Only the quirks differ,
+// the SCHIP and XO-CHIP instructions are not implemented.
+func (p Platform) Quirks() Quirks {
+	switch p {
+	case PLATFORM_VIP:
+		return Quirks{ShiftVy: true, VFReset: true, Clip: true, VBlankWait: true}
+	case PLATFORM_SCHIP:
+		return Quirks{KeepIndex: true, JumpVx: true, Clip: true}
+	case PLATFORM_XOCHIP:
+		return Quirks{ShiftVy: true}
+	}
+	return Quirks{}
+}
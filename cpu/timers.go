@@ -1,18 +1,19 @@
 package cpu
 
-import (
-	"fmt"
-)
-
 func (c *Chip8) UpdateTimers() {
+	c.waitVBlank = false
+
 	if c.delayTimer > 0 {
 		c.delayTimer = c.delayTimer - 1
 	}
 
 	if c.soundTimer > 0 {
 		c.soundTimer = c.soundTimer - 1
-		if c.soundTimer == 1 {
-			fmt.Println("BEEP")
-		}
 	}
 }
+
+// SoundActive reports whether the buzzer should sound, which is while the
+// sound timer is running.
+func (c *Chip8) SoundActive() bool {
+	return c.soundTimer > 0
+}
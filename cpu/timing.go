@@ -0,0 +1,161 @@
+package cpu
+
+type TimingMode uint8
+
+const (
+	TIMING_FIXED TimingMode = iota // cyclesPerFrame instructions per frame
+	TIMING_VIP                     // COSMAC VIP machine-cycle budget per frame
+)
+
+/*
+The VIP CPU (RCA 1802) runs at 1.76 MHz with 8 clocks per machine cycle, and
+the CDP1861 display produces a frame every 262 lines of 14 machine cycles.
+While the display is active, DMA steals 8 cycles on each of the 128 visible
+lines and the interrupt routine updates the timers, leaving the rest of the
+frame to the interpreter.
+*/
+const VIP_CYCLES_PER_FRAME = 3668
+const VIP_DISPLAY_CYCLES = 128*8 + 46
+const VIP_FETCH_CYCLES = 40
+
+func ParseTimingMode(name string) (TimingMode, bool) {
+	switch name {
+	case "fixed":
+		return TIMING_FIXED, true
+	case "vip":
+		return TIMING_VIP, true
+	}
+	return TIMING_FIXED, false
+}
+
+func (t TimingMode) String() string {
+	switch t {
+	case TIMING_VIP:
+		return "vip"
+	default:
+		return "fixed"
+	}
+}
+
+func (c *Chip8) TimingMode() TimingMode {
+	return c.timing
+}
+
+func (c *Chip8) SetTimingMode(t TimingMode) {
+	c.timing = t
+	c.cycleBudget = 0
+}
+
+// runVipFrame executes instructions until the machine cycles available to the
+// interpreter in one VIP frame are used. Instructions that overrun the frame
+// borrow from the next one.
+func (c *Chip8) runVipFrame() {
+	c.cycleBudget += VIP_CYCLES_PER_FRAME - VIP_DISPLAY_CYCLES
+
+	for c.cycleBudget > 0 {
+		opcode := c.fetchOpcode()
+		cost := c.vipCycles(opcode)
+
+		c.Cycle()
+		c.cycleBudget -= VIP_FETCH_CYCLES
+
+		if opcode&0xF000 == 0xD000 {
+			// The interpreter waits for the display interrupt before drawing,
+			// so the rest of this frame is idle and the sprite is drawn with
+			// the cycles of the next one.
+			c.cycleBudget = -cost
+			return
+		}
+
+		c.cycleBudget -= cost
+	}
+}
+
+// vipCycles returns the approximate number of machine cycles the VIP
+// interpreter spends executing opcode, excluding VIP_FETCH_CYCLES. It must be
+// called before the instruction is executed.
+func (c *Chip8) vipCycles(opcode uint16) int {
+	kk := uint8(opcode & 0x00FF)
+	x := (opcode & 0x0F00) >> 8
+	y := (opcode & 0x00F0) >> 4
+	n := opcode & 0x000F
+
+	skip := func(taken bool) int {
+		if taken {
+			return 14
+		}
+		return 10
+	}
+
+	switch opcode & 0xF000 {
+	case 0x0000:
+		switch opcode {
+		case 0x00E0:
+			return 3078
+		case 0x00EE:
+			return 10
+		}
+		// 0nnn runs native 1802 code, which we don't emulate
+		return 0
+	case 0x1000:
+		return 12
+	case 0x2000:
+		return 26
+	case 0x3000:
+		return skip(c.register[x] == kk)
+	case 0x4000:
+		return skip(c.register[x] != kk)
+	case 0x5000:
+		return skip(c.register[x] == c.register[y]) + 4
+	case 0x6000:
+		return 6
+	case 0x7000:
+		return 10
+	case 0x8000:
+		return 44
+	case 0x9000:
+		return skip(c.register[x] != c.register[y]) + 4
+	case 0xA000:
+		return 12
+	case 0xB000:
+		return 22
+	case 0xC000:
+		return 36
+	case 0xD000:
+		return c.vipDrawCycles(x, n)
+	case 0xE000:
+		pressed := c.keypad[c.register[x]&0xF] == 1
+		if kk == 0xA1 {
+			pressed = !pressed
+		}
+		return skip(pressed) + 4
+	case 0xF000:
+		switch kk {
+		case 0x0A:
+			return 19
+		case 0x1E, 0x29:
+			return 16
+		case 0x33:
+			v := int(c.register[x])
+			return 80 + 16*(v/100+(v/10)%10+v%10)
+		case 0x55, 0x65:
+			return 14 + 14*int(x+1)
+		}
+		return 10
+	}
+
+	return 0
+}
+
+// vipDrawCycles estimates Dxyn: every sprite row is shifted into place one bit
+// at a time, and unaligned rows touch two display bytes instead of one.
+func (c *Chip8) vipDrawCycles(x uint16, n uint16) int {
+	shift := int(c.register[x] % 8)
+
+	row := 30 + 16
+	if shift != 0 {
+		row += 8*shift + 16
+	}
+
+	return 26 + int(n)*row
+}
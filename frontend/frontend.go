@@ -0,0 +1,122 @@
+package frontend
+
+import (
+	"fmt"
+	"github.com/brunocroh/chip8/cpu"
+	"github.com/brunocroh/chip8/keymap"
+	"github.com/brunocroh/chip8/render"
+	"github.com/brunocroh/chip8/tty"
+	"image"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+Frontend is where the emulator meets the user: it shows frames, feeds key
+presses to the CPU, plays the buzzer and tells when to stop. The emulation
+loop, timing and post-processing are shared, so a frontend only has to
+handle its own window, terminal or lack of either.
+
+Frontends register themselves by name, the SDL one only when built with
+cgo, so CGO_ENABLED=0 builds still get the terminal and null frontends.
+*/
+type Frontend interface {
+	// Present shows a frame after persistence and effects. The palette is
+	// for anything drawn around it, like letterboxing.
+	Present(frame *image.RGBA, palette render.Palette) error
+
+	// Poll sends the input received since the last call to chip8 and
+	// returns the hotkeys pressed.
+	Poll(chip8 *cpu.Chip8) []Action
+
+	// Sound turns the buzzer on or off, it is called every frame.
+	Sound(active bool)
+
+	// Status shows a short message, like the path of a screenshot. The
+	// terminal is in raw mode while the terminal frontend runs, so nothing
+	// else may print.
+	Status(message string)
+
+	// Quit reports whether the user asked to quit.
+	Quit() bool
+
+	Close() error
+}
+
+// Options are the settings a frontend may use, each one ignores those that
+// don't apply to it.
+type Options struct {
+	Title          string
+	Scale          int  // Initial window size as a multiple of 64x32
+	IntegerScaling bool // Only scale by whole multiples
+	Keys           keymap.Keymap
+	ControllerKeys keymap.Keymap
+	TerminalMode   tty.Mode
+	KeyHold        time.Duration // How long terminal keys stay pressed
+}
+
+// Action is an emulator shortcut, handled by the shared loop.
+type Action uint8
+
+const (
+	ACTION_NEXT_PALETTE Action = iota
+	ACTION_TOGGLE_FILTERS
+	ACTION_TOGGLE_RECORDING
+	ACTION_TOGGLE_FULLSCREEN // Handled by the frontend when it can
+	ACTION_SCREENSHOT
+)
+
+// Hotkeys maps key names, as returned by keymap.Normalize, to actions.
+var Hotkeys = map[string]Action{
+	"f2":  ACTION_NEXT_PALETTE,
+	"f3":  ACTION_TOGGLE_FILTERS,
+	"f10": ACTION_TOGGLE_RECORDING,
+	"f11": ACTION_TOGGLE_FULLSCREEN,
+	"f12": ACTION_SCREENSHOT,
+}
+
+var frontends = map[string]func(Options) (Frontend, error){}
+
+func Register(name string, open func(Options) (Frontend, error)) {
+	frontends[name] = open
+}
+
+func Open(name string, opts Options) (Frontend, error) {
+	open, ok := frontends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown frontend %q, available: %s", name, strings.Join(Available(), ", "))
+	}
+	return open(opts)
+}
+
+func Available() []string {
+	names := make([]string, 0, len(frontends))
+	for name := range frontends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Default is the SDL frontend when it was built in, the terminal otherwise.
+func Default() string {
+	if _, ok := frontends["sdl"]; ok {
+		return "sdl"
+	}
+	return "tty"
+}
+
+// press sends a key change to chip8 when name is bound in keys.
+func press(chip8 *cpu.Chip8, keys keymap.Keymap, name string, pressed bool) {
+	key, ok := keys.Lookup(name)
+	if !ok {
+		return
+	}
+
+	if pressed {
+		chip8.OnKeyEvent(key, 1)
+	} else {
+		chip8.OnKeyEvent(key, 0)
+	}
+}
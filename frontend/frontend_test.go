@@ -0,0 +1,19 @@
+package frontend
+
+import "testing"
+
+func TestOpen(t *testing.T) {
+	fe, err := Open("null", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fe.Close()
+
+	if fe.Quit() {
+		t.Error("null frontend wants to quit")
+	}
+
+	if _, err := Open("nope", Options{}); err == nil {
+		t.Error("Open accepted an unknown frontend")
+	}
+}
@@ -0,0 +1,24 @@
+package frontend
+
+import (
+	"fmt"
+	"github.com/brunocroh/chip8/cpu"
+	"github.com/brunocroh/chip8/render"
+	"image"
+	"os"
+)
+
+func init() {
+	Register("null", func(Options) (Frontend, error) { return Null{}, nil })
+}
+
+// Null has no output or input, for running ROMs headless, e.g. to record a
+// GIF or in tests. It never asks to quit.
+type Null struct{}
+
+func (Null) Present(*image.RGBA, render.Palette) error { return nil }
+func (Null) Poll(*cpu.Chip8) []Action                  { return nil }
+func (Null) Sound(bool)                                {}
+func (Null) Status(message string)                     { fmt.Fprintln(os.Stderr, message) }
+func (Null) Quit() bool                                { return false }
+func (Null) Close() error                              { return nil }
@@ -0,0 +1,141 @@
+//go:build cgo && !nosdl
+// +build cgo,!nosdl
+
+package frontend
+
+import (
+	"fmt"
+	"github.com/brunocroh/chip8/cpu"
+	"github.com/brunocroh/chip8/keymap"
+	"github.com/brunocroh/chip8/render"
+	"image"
+	"os"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+func init() {
+	Register("sdl", openSDL)
+}
+
+// SDL shows a resizable window, reads the keyboard and game controllers and
+// plays the buzzer as a square wave. Build with -tags nosdl to leave it out
+// when SDL isn't installed.
+type SDL struct {
+	screen   *display
+	pads     *controllers
+	buzzer   *buzzer
+	keys     keymap.Keymap
+	palette  render.Palette
+	quitting bool
+}
+
+func openSDL(opts Options) (Frontend, error) {
+	if err := sdl.Init(sdl.INIT_VIDEO | sdl.INIT_AUDIO | sdl.INIT_GAMECONTROLLER); err != nil {
+		return nil, err
+	}
+
+	screen, err := newDisplay(opts.Title, int32(opts.Scale), opts.IntegerScaling)
+	if err != nil {
+		sdl.Quit()
+		return nil, err
+	}
+
+	// The emulator is still playable without sound
+	buzzer, err := newBuzzer()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Fail to open audio:", err)
+	}
+
+	return &SDL{
+		screen:  screen,
+		pads:    newControllers(opts.ControllerKeys),
+		buzzer:  buzzer,
+		keys:    opts.Keys,
+		palette: render.DefaultPalette,
+	}, nil
+}
+
+func (s *SDL) Present(frame *image.RGBA, palette render.Palette) error {
+	s.palette = palette
+	if err := s.screen.update(frame); err != nil {
+		return err
+	}
+
+	s.screen.present(palette)
+	return nil
+}
+
+func (s *SDL) Poll(chip8 *cpu.Chip8) []Action {
+	var actions []Action
+
+	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+		switch et := event.(type) {
+		case *sdl.KeyboardEvent:
+			if et.Repeat != 0 {
+				continue
+			}
+
+			name := keymap.Normalize(sdl.GetKeyName(et.Keysym.Sym))
+			pressed := et.Type == sdl.KEYDOWN
+
+			if action, ok := Hotkeys[name]; ok {
+				if pressed {
+					actions = s.hotkey(actions, action)
+				}
+				continue
+			}
+
+			press(chip8, s.keys, name, pressed)
+		case *sdl.WindowEvent:
+			// Frames are only presented when they change
+			if et.Event == sdl.WINDOWEVENT_EXPOSED || et.Event == sdl.WINDOWEVENT_SIZE_CHANGED {
+				s.screen.present(s.palette)
+			}
+		case *sdl.QuitEvent:
+			s.quitting = true
+		default:
+			s.pads.handleEvent(chip8, event)
+		}
+	}
+
+	return actions
+}
+
+// hotkey handles the actions that belong to the window and passes the rest
+// on
+func (s *SDL) hotkey(actions []Action, action Action) []Action {
+	if action != ACTION_TOGGLE_FULLSCREEN {
+		return append(actions, action)
+	}
+
+	if err := s.screen.toggleFullscreen(); err != nil {
+		fmt.Fprintln(os.Stderr, "Fail to toggle fullscreen:", err)
+	}
+	return actions
+}
+
+func (s *SDL) Sound(active bool) {
+	if s.buzzer != nil {
+		s.buzzer.set(active)
+	}
+}
+
+// Status goes to stderr, the window has no room for text.
+func (s *SDL) Status(message string) {
+	fmt.Fprintln(os.Stderr, message)
+}
+
+func (s *SDL) Quit() bool {
+	return s.quitting
+}
+
+func (s *SDL) Close() error {
+	if s.buzzer != nil {
+		s.buzzer.close()
+	}
+	s.pads.close()
+	s.screen.destroy()
+	sdl.Quit()
+	return nil
+}
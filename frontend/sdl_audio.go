@@ -0,0 +1,85 @@
+//go:build cgo && !nosdl
+// +build cgo,!nosdl
+
+package frontend
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	SAMPLE_RATE    = 44100
+	BUZZER_PITCH   = 440 // Hz
+	BUZZER_VOLUME  = 24  // Out of 127
+	BUZZER_BUFFERS = 3   // Frames of sound queued ahead
+)
+
+// buzzer plays a square wave while the sound timer runs. Samples are queued
+// a few frames ahead instead of generated in a callback, which would run on
+// an SDL thread outside of Go.
+type buzzer struct {
+	device sdl.AudioDeviceID
+	phase  int
+	active bool
+}
+
+func newBuzzer() (*buzzer, error) {
+	spec := sdl.AudioSpec{
+		Freq:     SAMPLE_RATE,
+		Format:   sdl.AUDIO_S8,
+		Channels: 1,
+		Samples:  512,
+	}
+
+	device, err := sdl.OpenAudioDevice("", false, &spec, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &buzzer{device: device}, nil
+}
+
+// set is called once per frame, topping up the queue while active
+func (b *buzzer) set(active bool) {
+	if !active {
+		if b.active {
+			sdl.PauseAudioDevice(b.device, true)
+			sdl.ClearQueuedAudio(b.device)
+			b.active = false
+		}
+		return
+	}
+
+	frame := SAMPLE_RATE / 60
+	queued := int(sdl.GetQueuedAudioSize(b.device))
+	if queued < BUZZER_BUFFERS*frame {
+		sdl.QueueAudio(b.device, b.wave(BUZZER_BUFFERS*frame-queued))
+	}
+
+	if !b.active {
+		sdl.PauseAudioDevice(b.device, false)
+		b.active = true
+	}
+}
+
+// wave returns the next n samples of the square wave, continuing where the
+// last ones stopped so there are no clicks
+func (b *buzzer) wave(n int) []byte {
+	period := SAMPLE_RATE / BUZZER_PITCH
+	samples := make([]byte, n)
+
+	for i := range samples {
+		if b.phase < period/2 {
+			samples[i] = BUZZER_VOLUME
+		} else {
+			samples[i] = byte(256 - BUZZER_VOLUME)
+		}
+		b.phase = (b.phase + 1) % period
+	}
+
+	return samples
+}
+
+func (b *buzzer) close() {
+	sdl.CloseAudioDevice(b.device)
+}
@@ -0,0 +1,115 @@
+//go:build cgo && !nosdl
+// +build cgo,!nosdl
+
+package frontend
+
+import (
+	"fmt"
+	"github.com/brunocroh/chip8/cpu"
+	"github.com/brunocroh/chip8/keymap"
+	"os"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// How far an analog axis must move before it counts as a key press
+const AXIS_THRESHOLD = 16000
+
+// controllers tracks the connected game controllers. SDL reports controllers
+// present at startup with the same added event as hot-plugged ones.
+type controllers struct {
+	mapping keymap.Keymap
+	open    map[sdl.JoystickID]*sdl.GameController
+	held    map[sdl.JoystickID]map[string]bool
+}
+
+func newControllers(mapping keymap.Keymap) *controllers {
+	return &controllers{
+		mapping: mapping,
+		open:    map[sdl.JoystickID]*sdl.GameController{},
+		held:    map[sdl.JoystickID]map[string]bool{},
+	}
+}
+
+// handleEvent feeds controller events into chip8 and reports whether event
+// was a controller event.
+func (c *controllers) handleEvent(chip8 *cpu.Chip8, event sdl.Event) bool {
+	switch et := event.(type) {
+	case *sdl.ControllerDeviceEvent:
+		switch et.Type {
+		case sdl.CONTROLLERDEVICEADDED:
+			c.connect(int(et.Which))
+		case sdl.CONTROLLERDEVICEREMOVED:
+			c.disconnect(chip8, et.Which)
+		}
+	case *sdl.ControllerButtonEvent:
+		name := sdl.GameControllerGetStringForButton(sdl.GameControllerButton(et.Button))
+		c.set(chip8, et.Which, name, et.State == sdl.PRESSED)
+	case *sdl.ControllerAxisEvent:
+		name := sdl.GameControllerGetStringForAxis(sdl.GameControllerAxis(et.Axis))
+		c.set(chip8, et.Which, name+"-", et.Value < -AXIS_THRESHOLD)
+		c.set(chip8, et.Which, name+"+", et.Value > AXIS_THRESHOLD)
+	default:
+		return false
+	}
+
+	return true
+}
+
+func (c *controllers) connect(index int) {
+	controller := sdl.GameControllerOpen(index)
+	if controller == nil {
+		fmt.Fprintln(os.Stderr, "Fail to open controller:", sdl.GetError())
+		return
+	}
+
+	id := controller.Joystick().InstanceID()
+	if _, ok := c.open[id]; ok {
+		controller.Close()
+		return
+	}
+
+	c.open[id] = controller
+	c.held[id] = map[string]bool{}
+	fmt.Fprintln(os.Stderr, "Controller connected:", controller.Name())
+}
+
+func (c *controllers) disconnect(chip8 *cpu.Chip8, id sdl.JoystickID) {
+	controller, ok := c.open[id]
+	if !ok {
+		return
+	}
+
+	// Don't leave keys stuck down when a controller is unplugged mid-press
+	for name := range c.held[id] {
+		c.set(chip8, id, name, false)
+	}
+
+	fmt.Fprintln(os.Stderr, "Controller disconnected:", controller.Name())
+	controller.Close()
+	delete(c.open, id)
+	delete(c.held, id)
+}
+
+// set presses or releases the keypad key bound to the controller input name,
+// only sending an event when its state changes.
+func (c *controllers) set(chip8 *cpu.Chip8, id sdl.JoystickID, name string, active bool) {
+	held, ok := c.held[id]
+	if !ok || held[name] == active {
+		return
+	}
+
+	if active {
+		held[name] = true
+	} else {
+		delete(held, name)
+	}
+
+	press(chip8, c.mapping, name, active)
+}
+
+func (c *controllers) close() {
+	for _, controller := range c.open {
+		controller.Close()
+	}
+}
@@ -0,0 +1,113 @@
+//go:build cgo && !nosdl
+// +build cgo,!nosdl
+
+package frontend
+
+import (
+	"github.com/brunocroh/chip8/render"
+	"image"
+	"unsafe"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// display draws the framebuffer through a streaming texture. The renderer's
+// logical size keeps the 2:1 aspect ratio, letterboxing the rest of the
+// window, and with integer scaling only whole multiples of 64x32 are used.
+type display struct {
+	window     *sdl.Window
+	renderer   *sdl.Renderer
+	texture    *sdl.Texture
+	size       image.Point
+	fullscreen bool
+}
+
+// newDisplay opens a window scale times the size of the display. Frames of
+// any size are scaled to the 64x32 logical size of the renderer.
+func newDisplay(title string, scale int32, integer bool) (*display, error) {
+	window, err := sdl.CreateWindow(title, sdl.WINDOWPOS_CENTERED, sdl.WINDOWPOS_CENTERED,
+		render.WIDTH*scale, render.HEIGHT*scale, sdl.WINDOW_SHOWN|sdl.WINDOW_RESIZABLE|sdl.WINDOW_ALLOW_HIGHDPI)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep pixels sharp when the texture is scaled
+	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "nearest")
+
+	renderer, err := sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED|sdl.RENDERER_PRESENTVSYNC)
+	if err != nil {
+		window.Destroy()
+		return nil, err
+	}
+
+	d := &display{
+		window:   window,
+		renderer: renderer,
+	}
+
+	if err := renderer.SetLogicalSize(render.WIDTH, render.HEIGHT); err != nil {
+		d.destroy()
+		return nil, err
+	}
+
+	if err := renderer.SetIntegerScale(integer); err != nil {
+		d.destroy()
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// update uploads frame to the texture, which is created again when the
+// frame size changes, e.g. when filters are toggled.
+func (d *display) update(frame *image.RGBA) error {
+	size := frame.Bounds().Size()
+	if d.texture == nil || size != d.size {
+		if d.texture != nil {
+			d.texture.Destroy()
+		}
+
+		texture, err := d.renderer.CreateTexture(sdl.PIXELFORMAT_RGBA32, sdl.TEXTUREACCESS_STREAMING, int32(size.X), int32(size.Y))
+		if err != nil {
+			d.texture = nil
+			return err
+		}
+		d.texture, d.size = texture, size
+	}
+
+	return d.texture.Update(nil, unsafe.Pointer(&frame.Pix[0]), frame.Stride)
+}
+
+// present draws the texture, waiting for vsync. The letterbox uses the
+// palette background.
+func (d *display) present(palette render.Palette) {
+	background := palette.Color(0)
+	d.renderer.SetDrawColor(background.R, background.G, background.B, background.A)
+	d.renderer.Clear()
+	if d.texture != nil {
+		d.renderer.Copy(d.texture, nil, nil)
+	}
+	d.renderer.Present()
+}
+
+func (d *display) toggleFullscreen() error {
+	var flags uint32
+	if !d.fullscreen {
+		flags = sdl.WINDOW_FULLSCREEN_DESKTOP
+	}
+
+	if err := d.window.SetFullscreen(flags); err != nil {
+		return err
+	}
+
+	d.fullscreen = !d.fullscreen
+	return nil
+}
+
+func (d *display) destroy() {
+	if d.texture != nil {
+		d.texture.Destroy()
+	}
+	d.renderer.Destroy()
+	d.window.Destroy()
+}
@@ -0,0 +1,97 @@
+package frontend
+
+import (
+	"github.com/brunocroh/chip8/cpu"
+	"github.com/brunocroh/chip8/keymap"
+	"github.com/brunocroh/chip8/render"
+	"github.com/brunocroh/chip8/tty"
+	"image"
+)
+
+func init() {
+	Register("tty", openTerminal)
+}
+
+// Terminal draws in the terminal it was started from, see the tty package.
+type Terminal struct {
+	term     *tty.Terminal
+	keys     keymap.Keymap
+	frame    []byte
+	beeping  bool
+	quitting bool
+}
+
+func openTerminal(opts Options) (Frontend, error) {
+	term, err := tty.Open(opts.TerminalMode)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.KeyHold > 0 {
+		term.KeyHold = opts.KeyHold
+	}
+
+	return &Terminal{
+		term:  term,
+		keys:  opts.Keys,
+		frame: make([]byte, render.WIDTH*render.HEIGHT*4),
+	}, nil
+}
+
+// Present samples frame back down to 64x32, terminal cells are too coarse
+// for filters to show anyway.
+func (t *Terminal) Present(frame *image.RGBA, palette render.Palette) error {
+	size := frame.Bounds().Size()
+	for y := 0; y < render.HEIGHT; y++ {
+		for x := 0; x < render.WIDTH; x++ {
+			sx := (2*x + 1) * size.X / (2 * render.WIDTH)
+			sy := (2*y + 1) * size.Y / (2 * render.HEIGHT)
+			i := frame.PixOffset(sx, sy)
+			copy(t.frame[(y*render.WIDTH+x)*4:], frame.Pix[i:i+4])
+		}
+	}
+
+	return t.term.Draw(t.frame, palette)
+}
+
+func (t *Terminal) Poll(chip8 *cpu.Chip8) []Action {
+	events, quit := t.term.Poll()
+	if quit {
+		t.quitting = true
+	}
+
+	var actions []Action
+	for _, e := range events {
+		if action, ok := Hotkeys[e.Name]; ok {
+			if e.Pressed {
+				actions = append(actions, action)
+			}
+			continue
+		}
+
+		press(chip8, t.keys, e.Name, e.Pressed)
+	}
+
+	return actions
+}
+
+// Sound rings the bell when the buzzer starts.
+func (t *Terminal) Sound(active bool) {
+	if active && !t.beeping {
+		t.term.Bell()
+	}
+	t.beeping = active
+}
+
+// Status is shown below the display.
+func (t *Terminal) Status(message string) {
+	t.term.Status(message)
+}
+
+func (t *Terminal) Quit() bool {
+	return t.quitting
+}
+
+func (t *Terminal) Close() error {
+	return t.term.Close()
+}
@@ -0,0 +1,294 @@
+package keymap
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const DEFAULT_LAYOUT = "qwerty"
+
+// Keymap maps a keyboard key name, as returned by Normalize, to a CHIP-8
+// keypad key (0x0-0xF).
+type Keymap map[string]uint8
+
+/*
+The presets keep the physical position of the classic layout:
+
+	CHIP-8 Keypad    QWERTY
+	1 2 3 C          1 2 3 4
+	4 5 6 D    =>    Q W E R
+	7 8 9 E          A S D F
+	A 0 B F          Z X C V
+*/
+var Presets = map[string]Keymap{
+	"qwerty": {
+		"1": 0x1, "2": 0x2, "3": 0x3, "4": 0xC,
+		"q": 0x4, "w": 0x5, "e": 0x6, "r": 0xD,
+		"a": 0x7, "s": 0x8, "d": 0x9, "f": 0xE,
+		"z": 0xA, "x": 0x0, "c": 0xB, "v": 0xF,
+	},
+	"azerty": {
+		"1": 0x1, "2": 0x2, "3": 0x3, "4": 0xC,
+		"&": 0x1, "é": 0x2, "\"": 0x3, "'": 0xC,
+		"a": 0x4, "z": 0x5, "e": 0x6, "r": 0xD,
+		"q": 0x7, "s": 0x8, "d": 0x9, "f": 0xE,
+		"w": 0xA, "x": 0x0, "c": 0xB, "v": 0xF,
+	},
+	"dvorak": {
+		"1": 0x1, "2": 0x2, "3": 0x3, "4": 0xC,
+		"'": 0x4, ",": 0x5, ".": 0x6, "p": 0xD,
+		"a": 0x7, "o": 0x8, "e": 0x9, "u": 0xE,
+		";": 0xA, "q": 0x0, "j": 0xB, "k": 0xF,
+	},
+}
+
+/*
+Controller maps game controller inputs to the keypad. Buttons use the SDL
+GameController names (a, b, x, y, back, start, leftshoulder, dpup, ...) and
+analog axes (leftx, lefty, rightx, righty, lefttrigger, righttrigger) are
+bound per direction with a "-" or "+" suffix.
+
+The default follows the common 5/7/8/9 movement and 4/6 action keys.
+*/
+var Controller = Keymap{
+	"dpup": 0x5, "dpdown": 0x8, "dpleft": 0x7, "dpright": 0x9,
+	"lefty-": 0x5, "lefty+": 0x8, "leftx-": 0x7, "leftx+": 0x9,
+	"a": 0x6, "b": 0x4, "x": 0xA, "y": 0xB,
+	"leftshoulder": 0x1, "rightshoulder": 0xC,
+	"back": 0x0, "start": 0xF,
+}
+
+/*
+Config is the JSON keymap file:
+
+	{
+	  "layout": "azerty",
+	  "keys": { "arrowup": "5", "arrowdown": "8" },
+	  "controller": { "a": "5" },
+	  "roms": {
+	    "pong.ch8": {
+	      "keys": { "w": "1", "s": "4" },
+	      "controller": { "lefty-": "1", "lefty+": "4", "righty-": "C", "righty+": "D" }
+	    }
+	  }
+	}
+
+Keys and controller inputs are bound to hexadecimal keypad digits on top of
+the layout preset and the Controller defaults, and entries in roms, matched by
+ROM file name, override the top level settings.
+*/
+type Config struct {
+	Layout     string             `json:"layout,omitempty"`
+	Keys       map[string]string  `json:"keys,omitempty"`
+	Controller map[string]string  `json:"controller,omitempty"`
+	Roms       map[string]*Config `json:"roms,omitempty"`
+}
+
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data)
+}
+
+func Parse(data []byte) (*Config, error) {
+	config := &Config{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("keymap: %w", err)
+	}
+
+	// Resolve once so mistakes are reported when the file is loaded
+	roms := []string{""}
+	for rom := range config.Roms {
+		roms = append(roms, rom)
+	}
+	for _, rom := range roms {
+		if _, err := config.Keymap(rom); err != nil {
+			return nil, err
+		}
+		if _, err := config.ControllerKeymap(rom); err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+// Keymap resolves the keymap for rom, which may be empty. A nil Config
+// resolves to the default layout.
+func (c *Config) Keymap(rom string) (Keymap, error) {
+	layout := DEFAULT_LAYOUT
+	var overrides []map[string]string
+
+	if c != nil {
+		if c.Layout != "" {
+			layout = c.Layout
+		}
+		overrides = append(overrides, c.Keys)
+
+		if romConfig, ok := c.Roms[rom]; ok && romConfig != nil {
+			if romConfig.Layout != "" {
+				layout = romConfig.Layout
+			}
+			overrides = append(overrides, romConfig.Keys)
+		}
+	}
+
+	preset, ok := Presets[strings.ToLower(layout)]
+	if !ok {
+		return nil, fmt.Errorf("keymap: unknown layout %q", layout)
+	}
+
+	return bind(preset, overrides)
+}
+
+// ControllerKeymap resolves the game controller mapping for rom, which may be
+// empty. A nil Config resolves to the Controller defaults.
+func (c *Config) ControllerKeymap(rom string) (Keymap, error) {
+	var overrides []map[string]string
+
+	if c != nil {
+		overrides = append(overrides, c.Controller)
+
+		if romConfig, ok := c.Roms[rom]; ok && romConfig != nil {
+			overrides = append(overrides, romConfig.Controller)
+		}
+	}
+
+	return bind(Controller, overrides)
+}
+
+// WithLayout returns a copy of c, which may be nil, using the layout preset
+// named layout. Bindings and per-ROM settings of c are kept.
+func (c *Config) WithLayout(layout string) (*Config, error) {
+	if _, ok := Presets[strings.ToLower(layout)]; !ok {
+		return nil, fmt.Errorf("keymap: unknown layout %q", layout)
+	}
+
+	config := &Config{}
+	if c != nil {
+		*config = *c
+	}
+	config.Layout = layout
+
+	return config, nil
+}
+
+/*
+Hints are the keypad keys a ROM uses for each role, as listed by the ROM
+database: up, down, left, right, a and b, and the same for player 2
+(player2Up, ...). HintKeys and HintButtons bind the roles to keyboard keys
+and controller inputs.
+*/
+type Hints map[string]uint8
+
+var HintKeys = map[string][]string{
+	"up": {"arrowup"}, "down": {"arrowdown"}, "left": {"arrowleft"}, "right": {"arrowright"},
+	"a": {"space"}, "b": {"enter"},
+}
+
+var HintButtons = map[string][]string{
+	"up": {"dpup", "lefty-"}, "down": {"dpdown", "lefty+"},
+	"left": {"dpleft", "leftx-"}, "right": {"dpright", "leftx+"},
+	"a": {"a"}, "b": {"b"},
+	"player2Up": {"righty-"}, "player2Down": {"righty+"},
+	"player2Left": {"rightx-"}, "player2Right": {"rightx+"},
+}
+
+// WithHints returns a copy of c, which may be nil, with hints bound below
+// the settings of c, so the keymap file still has the last word.
+func (c *Config) WithHints(hints Hints) *Config {
+	hinted := &Config{}
+	if c != nil {
+		*hinted = *c
+	}
+
+	keys, controller := map[string]string{}, map[string]string{}
+	for role, key := range hints {
+		if key > 0xF {
+			continue
+		}
+		for _, name := range HintKeys[role] {
+			keys[name] = fmt.Sprintf("%X", key)
+		}
+		for _, name := range HintButtons[role] {
+			controller[name] = fmt.Sprintf("%X", key)
+		}
+	}
+
+	hinted.Keys = merge(keys, hinted.Keys)
+	hinted.Controller = merge(controller, hinted.Controller)
+	return hinted
+}
+
+// merge returns the bindings of base overridden by those of top
+func merge(base, top map[string]string) map[string]string {
+	merged := map[string]string{}
+	for name, key := range base {
+		merged[name] = key
+	}
+	for name, key := range top {
+		merged[Normalize(name)] = key
+	}
+	return merged
+}
+
+// ParseBindings parses a JSON object of key or controller names bound to
+// hexadecimal keypad digits, like the "keys" section of Config.
+func ParseBindings(data []byte) (Keymap, error) {
+	bindings := map[string]string{}
+	if err := json.Unmarshal(data, &bindings); err != nil {
+		return nil, fmt.Errorf("keymap: %w", err)
+	}
+
+	return bind(nil, []map[string]string{bindings})
+}
+
+func bind(base Keymap, overrides []map[string]string) (Keymap, error) {
+	keymap := Keymap{}
+	for name, key := range base {
+		keymap[name] = key
+	}
+
+	for _, keys := range overrides {
+		for name, value := range keys {
+			key, err := strconv.ParseUint(value, 16, 8)
+			if err != nil || key > 0xF {
+				return nil, fmt.Errorf("keymap: %q is not a keypad key (0-F) for %q", value, name)
+			}
+			keymap[Normalize(name)] = uint8(key)
+		}
+	}
+
+	return keymap, nil
+}
+
+// SDL key names that differ from the browser KeyboardEvent.key values
+var aliases = map[string]string{
+	" ":      "space",
+	"return": "enter",
+	"up":     "arrowup",
+	"down":   "arrowdown",
+	"left":   "arrowleft",
+	"right":  "arrowright",
+}
+
+// Normalize converts a key name from SDL or a browser KeyboardEvent.key into
+// the form used by Keymap.
+func Normalize(name string) string {
+	name = strings.ToLower(name)
+	if alias, ok := aliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+func (k Keymap) Lookup(name string) (uint8, bool) {
+	key, ok := k[Normalize(name)]
+	return key, ok
+}
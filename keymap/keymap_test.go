@@ -0,0 +1,129 @@
+package keymap
+
+import "testing"
+
+func TestConfigKeymap(t *testing.T) {
+	config, err := Parse([]byte(`{
+		"layout": "azerty",
+		"keys": { "ArrowUp": "5" },
+		"roms": { "pong.ch8": { "keys": { "w": "1" } } }
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keymap, err := config.Keymap("pong.ch8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]uint8{"A": 0x4, "Up": 0x5, "w": 0x1, "é": 0x2}
+	for name, want := range tests {
+		if got, ok := keymap.Lookup(name); !ok || got != want {
+			t.Errorf("Lookup(%q) = %#x, %v, want %#x", name, got, ok, want)
+		}
+	}
+
+	keymap, _ = config.Keymap("other.ch8")
+	if got, _ := keymap.Lookup("w"); got != 0xA {
+		t.Errorf("Expected rom override to apply only to pong.ch8")
+	}
+}
+
+func TestParseRejectsInvalidKey(t *testing.T) {
+	if _, err := Parse([]byte(`{"keys": {"w": "10"}}`)); err == nil {
+		t.Errorf("Expected error for keypad key out of range")
+	}
+
+	if _, err := Parse([]byte(`{"layout": "colemak"}`)); err == nil {
+		t.Errorf("Expected error for unknown layout")
+	}
+}
+
+func TestControllerKeymap(t *testing.T) {
+	config, err := Parse([]byte(`{
+		"roms": { "pong.ch8": { "controller": { "lefty-": "1", "lefty+": "4" } } }
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	controller, _ := config.ControllerKeymap("pong.ch8")
+	if got, _ := controller.Lookup("lefty-"); got != 0x1 {
+		t.Errorf("Expected rom profile binding, got %#x", got)
+	}
+
+	if got, _ := controller.Lookup("dpup"); got != 0x5 {
+		t.Errorf("Expected default binding to remain, got %#x", got)
+	}
+}
+
+func TestParseBindings(t *testing.T) {
+	bindings, err := ParseBindings([]byte(`{"A": "c"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := bindings["a"]; got != 0xC || len(bindings) != 1 {
+		t.Errorf("Unexpected bindings %v", bindings)
+	}
+}
+
+func TestWithHints(t *testing.T) {
+	config, err := Parse([]byte(`{"keys": {"ArrowUp": "2"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hinted := config.WithHints(Hints{"up": 0x5, "a": 0x6})
+	keys, err := hinted.Keymap("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keys["arrowup"] != 0x2 || keys["space"] != 0x6 {
+		t.Errorf("Expected the keymap file over the hints, got %v", keys)
+	}
+
+	controller, err := (*Config)(nil).WithHints(Hints{"up": 0x2}).ControllerKeymap("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if controller["dpup"] != 0x2 || controller["lefty-"] != 0x2 {
+		t.Errorf("Expected up hint on the controller, got %v", controller)
+	}
+}
+
+func TestWithLayout(t *testing.T) {
+	config, err := Parse([]byte(`{
+		"keys": { "ArrowUp": "5" },
+		"roms": { "pong.ch8": { "keys": { "w": "1" } } }
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dvorak, err := config.WithLayout("dvorak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Layout != "" {
+		t.Errorf("Expected the original config unchanged, got layout %q", config.Layout)
+	}
+
+	keymap, _ := dvorak.Keymap("pong.ch8")
+	tests := map[string]uint8{"ArrowUp": 0x5, "w": 0x1}
+	for name, want := range tests {
+		if got, ok := keymap.Lookup(name); !ok || got != want {
+			t.Errorf("Lookup(%q) = %#x, %v, want %#x", name, got, ok, want)
+		}
+	}
+	if got, _ := keymap.Lookup("'"); got != 0x4 {
+		t.Errorf("Expected ' bound to 4 by the dvorak layout")
+	}
+
+	if _, err := (*Config)(nil).WithLayout("colemak"); err == nil {
+		t.Errorf("Expected error for unknown layout")
+	}
+}
@@ -0,0 +1,92 @@
+// Buttons and axes of the standard Gamepad API mapping, named like the SDL
+// game controller inputs the Go keymap uses.
+const GAMEPAD_BUTTONS = [
+  "a",
+  "b",
+  "x",
+  "y",
+  "leftshoulder",
+  "rightshoulder",
+  "lefttrigger+",
+  "righttrigger+",
+  "back",
+  "start",
+  "leftstick",
+  "rightstick",
+  "dpup",
+  "dpdown",
+  "dpleft",
+  "dpright",
+  "guide",
+];
+const GAMEPAD_AXES = ["leftx", "lefty", "rightx", "righty"];
+const AXIS_THRESHOLD = 0.5;
+const GAMEPAD_MAPPING_KEY = "chip8.gamepadMapping";
+
+// Inputs currently held, per gamepad index
+const gamepadHeld = {};
+
+const setGamepadInput = (index, name, active) => {
+  const held = (gamepadHeld[index] ??= {});
+  if (!!held[name] === active) {
+    return;
+  }
+
+  held[name] = active;
+  window.onGamepadInput(name, active ? 1 : 0);
+};
+
+const pollGamepads = () => {
+  for (const gamepad of navigator.getGamepads()) {
+    if (!gamepad || gamepad.mapping !== "standard") {
+      continue;
+    }
+
+    gamepad.buttons.forEach((button, i) => {
+      if (GAMEPAD_BUTTONS[i]) {
+        setGamepadInput(gamepad.index, GAMEPAD_BUTTONS[i], button.pressed);
+      }
+    });
+
+    gamepad.axes.forEach((value, i) => {
+      if (GAMEPAD_AXES[i]) {
+        setGamepadInput(gamepad.index, GAMEPAD_AXES[i] + "-", value < -AXIS_THRESHOLD);
+        setGamepadInput(gamepad.index, GAMEPAD_AXES[i] + "+", value > AXIS_THRESHOLD);
+      }
+    });
+  }
+
+  requestAnimationFrame(pollGamepads);
+};
+
+const releaseGamepad = (index) => {
+  for (const name in gamepadHeld[index] ?? {}) {
+    setGamepadInput(index, name, false);
+  }
+  delete gamepadHeld[index];
+};
+
+// Loads the mapping saved in localStorage into the textarea and the emulator
+const setupGamepad = (mappingInput) => {
+  const saved = localStorage.getItem(GAMEPAD_MAPPING_KEY);
+  if (saved) {
+    mappingInput.value = saved;
+    window.setGamepadMapping(saved);
+  }
+
+  mappingInput.addEventListener("change", () => {
+    const mapping = mappingInput.value.trim() || "{}";
+    const error = window.setGamepadMapping(mapping);
+    if (error) {
+      alert(error);
+      return;
+    }
+    localStorage.setItem(GAMEPAD_MAPPING_KEY, mapping);
+  });
+
+  addEventListener("gamepaddisconnected", (event) => {
+    releaseGamepad(event.gamepad.index);
+  });
+
+  requestAnimationFrame(pollGamepads);
+};
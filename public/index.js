@@ -1,124 +1,174 @@
 const input = document.querySelector("#load-rom-input");
+const ipfInput = document.querySelector("#ipf-input");
+const timingSelect = document.querySelector("#timing-select");
+const vblankInput = document.querySelector("#vblank-input");
+const layoutSelect = document.querySelector("#layout-select");
+const keymapInput = document.querySelector("#keymap-input");
+const gamepadMappingInput = document.querySelector("#gamepad-mapping-input");
+const paletteSelect = document.querySelector("#palette-select");
+const backgroundInput = document.querySelector("#background-input");
+const foregroundInput = document.querySelector("#foreground-input");
+const persistenceSelect = document.querySelector("#persistence-select");
+const screenshotButton = document.querySelector("#screenshot-button");
+const filterInputs = document.querySelectorAll("[data-filter]");
+const touchpad = document.querySelector("#touchpad");
+const touchpadInput = document.querySelector("#touchpad-input");
+const touchpadLayoutSelect = document.querySelector("#touchpad-layout-select");
+const touchpadPositionSelect = document.querySelector(
+  "#touchpad-position-select",
+);
 const canvas = document.getElementById("canvas");
+const pageTitle = document.title;
 canvas.width = 1024;
 canvas.height = 512;
 const ctx = canvas.getContext("2d");
 const go = new Go();
 
-const clearCanvas = () => {
-  ctx.clearRect(0, 0, canvas.width, canvas.height);
-};
+// The emulator sends RGBA pixels, 64x32 or bigger with CRT filters, which
+// are scaled up when drawn on the canvas
+const screen = new OffscreenCanvas(64, 32);
+const screenCtx = screen.getContext("2d");
 
-const handleKeyPress = (key, value, cb) => {
-  let _key;
-  switch (key) {
-    case "1":
-      _key = 0x1;
-      break;
-    case "2":
-      _key = 0x2;
-      break;
-    case "3":
-      _key = 0x3;
-      break;
-    case "4":
-      _key = 0xc;
-      break;
-    case "q":
-      _key = 0x4;
-      break;
-    case "w":
-      _key = 0x5;
-      break;
-    case "e":
-      _key = 0x6;
-      break;
-    case "r":
-      _key = 0xd;
-      break;
-    case "a":
-      _key = 0x7;
-      break;
-    case "s":
-      _key = 0x8;
-      break;
-    case "d":
-      _key = 0x9;
-      break;
-    case "f":
-      _key = 0xe;
-      break;
-    case "z":
-      _key = 0xa;
-      break;
-    case "x":
-      _key = 0x0;
-      break;
-    case "c":
-      _key = 0xb;
-      break;
-    case "v":
-      _key = 0xf;
-      break;
-    default:
-      // key not map, just ignore
-      return;
+const renderCallback = (pixels, width, height) => {
+  if (screen.width !== width || screen.height !== height) {
+    screen.width = width;
+    screen.height = height;
   }
-  cb(_key, value);
-};
 
-const renderCallback = (_video) => {
-  const video = convertToUint32Array(_video);
-  clearCanvas();
-
-  for (i = 0; i < video.length; i++) {
-    if (video[i]) {
-      ctx.fillStyle = "white";
-    } else {
-      ctx.fillStyle = "black";
-    }
+  screenCtx.putImageData(
+    new ImageData(new Uint8ClampedArray(pixels.buffer), width, height),
+    0,
+    0,
+  );
 
-    const x = (i % 64) * 16;
-    const y = (i / 64) * 16;
-    const height = 16;
-    const width = height;
-
-    ctx.fillRect(x, y, width, height);
-  }
+  ctx.imageSmoothingEnabled = false;
+  ctx.drawImage(screen, 0, 0, canvas.width, canvas.height);
 };
 
-function convertToUint32Array(uint8Array) {
-  const uint32Array = new Uint32Array(2048);
-  const dataView = new DataView(uint8Array.buffer);
-  for (let i = 0; i < 2048; i++) {
-    uint32Array[i] = dataView.getUint32(i * 4, true);
-  }
-  return uint32Array;
-}
-
-const bufferMemory = new ArrayBuffer(8192);
-const videoMemory = new Uint8Array(bufferMemory);
-
 WebAssembly.instantiateStreaming(fetch("chip8.wasm"), go.importObject).then(
   (wasm) => {
     const { instance } = wasm;
     go.run(wasm.instance);
 
     addEventListener("keydown", (event) => {
-      handleKeyPress(event.key, 1, window.onKeyEvent);
+      if (!event.repeat && window.onKey(event.key, 1)) {
+        event.preventDefault();
+      }
     });
 
     addEventListener("keyup", (event) => {
-      handleKeyPress(event.key, 0, window.onKeyEvent);
+      window.onKey(event.key, 0);
+    });
+
+    setupGamepad(gamepadMappingInput);
+    setupTouchpad(
+      touchpad,
+      touchpadLayoutSelect,
+      touchpadPositionSelect,
+      touchpadInput,
+    );
+
+    for (const name of window.getPalettes()) {
+      paletteSelect.add(new Option(name, name));
+    }
+    paletteSelect.add(new Option("custom", "custom"));
+
+    const updatePalette = () => {
+      const custom = paletteSelect.value === "custom";
+      backgroundInput.hidden = !custom;
+      foregroundInput.hidden = !custom;
+      window.setPalette(
+        custom
+          ? `${backgroundInput.value},${foregroundInput.value}`
+          : paletteSelect.value,
+      );
+    };
+
+    paletteSelect.addEventListener("change", updatePalette);
+    backgroundInput.addEventListener("input", updatePalette);
+    foregroundInput.addEventListener("input", updatePalette);
+    updatePalette();
+
+    persistenceSelect.addEventListener("change", () => {
+      window.setPersistence(persistenceSelect.value);
     });
 
-    addEventListener("input", (event) => {
+    for (const filterInput of filterInputs) {
+      filterInput.addEventListener("change", () => {
+        const filters = [...filterInputs]
+          .filter((input) => input.checked)
+          .map((input) => input.dataset.filter);
+        window.setFilters(filters.join(","));
+      });
+    }
+
+    screenshotButton.addEventListener("click", () => {
+      const png = window.screenshot(8);
+      const romName = input.files[0]?.name.replace(/\.[^.]*$/, "") ?? "chip8";
+
+      const link = document.createElement("a");
+      link.href = URL.createObjectURL(new Blob([png], { type: "image/png" }));
+      link.download = `${romName}.png`;
+      link.click();
+      URL.revokeObjectURL(link.href);
+    });
+
+    // The layout applies on top of the keymap file, keeping its bindings
+    layoutSelect.addEventListener("change", () => {
+      const error = window.setKeymapLayout(layoutSelect.value);
+      if (error) {
+        alert(error);
+      }
+    });
+
+    keymapInput.addEventListener("input", async () => {
+      const error = window.setKeymapConfig(await keymapInput.files[0].text());
+      if (error) {
+        alert(error);
+      }
+    });
+
+    ipfInput.addEventListener("change", () => {
+      window.setCyclesPerFrame(Number(ipfInput.value));
+    });
+
+    timingSelect.addEventListener("change", () => {
+      window.setTimingMode(timingSelect.value);
+    });
+
+    vblankInput.addEventListener("change", () => {
+      window.setVBlankWait(vblankInput.checked);
+    });
+
+    input.addEventListener("input", (event) => {
       const fileReader = new FileReader();
       fileReader.readAsArrayBuffer(input.files[0]);
       fileReader.onload = () => {
         const rom = new Uint8Array(fileReader.result);
-        window.loadRom(rom);
-        window.start(() => renderCallback(videoMemory), videoMemory);
+        const info = window.loadRom(rom, input.files[0].name);
+        if (info && info.error) {
+          alert(info.error);
+          return;
+        }
+
+        // ROMs known to the ROM database come with their settings
+        document.title = info ? `${pageTitle} - ${info.title}` : pageTitle;
+        if (info) {
+          if (info.tickrate) {
+            ipfInput.value = info.tickrate;
+          }
+          vblankInput.checked = info.vblank;
+          if (info.colors) {
+            [backgroundInput.value, foregroundInput.value] = info.colors;
+            paletteSelect.value = "custom";
+            updatePalette();
+          }
+        }
+
+        window.setCyclesPerFrame(Number(ipfInput.value));
+        window.setTimingMode(timingSelect.value);
+        window.setVBlankWait(vblankInput.checked);
+        window.start(renderCallback);
       };
     });
   },
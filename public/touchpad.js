@@ -0,0 +1,115 @@
+const TOUCHPAD_LAYOUTS = {
+  // Same arrangement as the COSMAC VIP keypad
+  vip: [0x1, 0x2, 0x3, 0xc, 0x4, 0x5, 0x6, 0xd, 0x7, 0x8, 0x9, 0xe, 0xa, 0x0, 0xb, 0xf],
+  // Keys in hexadecimal order
+  sequential: [0x0, 0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8, 0x9, 0xa, 0xb, 0xc, 0xd, 0xe, 0xf],
+};
+
+// Keypad key held by each active touch
+const touchKeys = new Map();
+// Number of touches holding each keypad key
+const touchKeyCount = new Array(16).fill(0);
+
+const vibrate = () => {
+  if (navigator.vibrate) {
+    navigator.vibrate(10);
+  }
+};
+
+const touchpadKeyAt = (touch) => {
+  const element = document.elementFromPoint(touch.clientX, touch.clientY);
+  const button = element && element.closest("[data-key]");
+  return button ? Number(button.dataset.key) : undefined;
+};
+
+const setTouchpadKey = (touchpad, key, delta) => {
+  if (key === undefined) {
+    return;
+  }
+
+  const before = touchKeyCount[key];
+  touchKeyCount[key] = Math.max(0, before + delta);
+
+  // Several fingers on one key only press and release it once
+  if (before === 0 && touchKeyCount[key] === 1) {
+    window.onKeyEvent(key, 1);
+    vibrate();
+  } else if (before > 0 && touchKeyCount[key] === 0) {
+    window.onKeyEvent(key, 0);
+  }
+
+  touchpad
+    .querySelector(`[data-key="${key}"]`)
+    .classList.toggle("pressed", touchKeyCount[key] > 0);
+};
+
+const onTouch = (touchpad, event) => {
+  event.preventDefault();
+
+  for (const touch of event.changedTouches) {
+    const previous = touchKeys.get(touch.identifier);
+    const ended = event.type === "touchend" || event.type === "touchcancel";
+    const current = ended ? undefined : touchpadKeyAt(touch);
+
+    if (previous === current) {
+      continue;
+    }
+
+    // Sliding a finger moves the press to the key under it
+    setTouchpadKey(touchpad, previous, -1);
+    setTouchpadKey(touchpad, current, 1);
+
+    if (current === undefined) {
+      touchKeys.delete(touch.identifier);
+    } else {
+      touchKeys.set(touch.identifier, current);
+    }
+  }
+};
+
+// Lets go of every key held by a touch, the touches that held them no
+// longer count once the buttons under them are replaced
+const releaseTouchpadKeys = () => {
+  for (const [key, count] of touchKeyCount.entries()) {
+    if (count > 0) {
+      window.onKeyEvent(key, 0);
+    }
+  }
+  touchKeyCount.fill(0);
+  touchKeys.clear();
+};
+
+const renderTouchpad = (touchpad, layout) => {
+  releaseTouchpadKeys();
+  touchpad.replaceChildren(
+    ...TOUCHPAD_LAYOUTS[layout].map((key) => {
+      const button = document.createElement("button");
+      button.dataset.key = key;
+      button.textContent = key.toString(16).toUpperCase();
+      return button;
+    }),
+  );
+};
+
+const setupTouchpad = (touchpad, layoutSelect, positionSelect, visibleInput) => {
+  const update = () => {
+    renderTouchpad(touchpad, layoutSelect.value);
+    touchpad.dataset.position = positionSelect.value;
+    touchpad.hidden = !visibleInput.checked;
+  };
+
+  // Only show it by default where there probably is no keyboard
+  visibleInput.checked = matchMedia("(pointer: coarse)").matches;
+
+  layoutSelect.addEventListener("change", update);
+  positionSelect.addEventListener("change", update);
+  visibleInput.addEventListener("change", update);
+
+  for (const type of ["touchstart", "touchmove", "touchend", "touchcancel"]) {
+    touchpad.addEventListener(type, (event) => onTouch(touchpad, event), {
+      passive: false,
+    });
+  }
+
+  update();
+};
@@ -0,0 +1,278 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+)
+
+const DEFAULT_FILTER_SCALE = 4
+
+// Filter post-processes a frame upscaled by scale, where every CHIP-8 pixel
+// is a scale x scale block. It may modify img and return it, or return a new
+// image.
+type Filter interface {
+	Name() string
+	Apply(img *image.RGBA, scale int) *image.RGBA
+}
+
+// Filters are the available filters by name
+var Filters = map[string]Filter{
+	"scanlines": Scanlines{Strength: 0.4},
+	"grid":      Grid{Strength: 0.3},
+	"bloom":     Bloom{Strength: 0.6},
+	"curvature": Curvature{Amount: 0.08},
+}
+
+// Effects upscales 64x32 RGBA frames and runs them through Filters.
+type Effects struct {
+	Scale   int
+	Filters []Filter
+	Bypass  bool // Only upscale, skipping Filters
+
+	scaled *image.RGBA
+}
+
+// ParseEffects accepts a comma separated list of filter names, applied in
+// order, e.g. "scanlines,bloom,curvature". Without filters frames aren't
+// upscaled, as there is nothing to gain from it.
+func ParseEffects(s string, scale int) (*Effects, error) {
+	if scale < 1 {
+		return nil, fmt.Errorf("invalid filter scale %d", scale)
+	}
+
+	effects := &Effects{Scale: scale}
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || name == "none" {
+			continue
+		}
+
+		filter, ok := Filters[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown filter %q", name)
+		}
+		effects.Filters = append(effects.Filters, filter)
+	}
+
+	if len(effects.Filters) == 0 {
+		effects.Scale = 1
+	}
+
+	return effects, nil
+}
+
+// Size returns the dimensions of the frames returned by Apply.
+func (e *Effects) Size() (int, int) {
+	return WIDTH * e.Scale, HEIGHT * e.Scale
+}
+
+// Apply upscales frame, 64x32 RGBA pixels as written by Palette.Fill or
+// Persistence.Fill, and applies the filters. The returned image is reused by
+// the next call.
+func (e *Effects) Apply(frame []byte) *image.RGBA {
+	w, h := e.Size()
+	if e.scaled == nil || e.scaled.Rect.Dx() != w {
+		e.scaled = image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+
+	Scale(e.scaled, frame, e.Scale)
+
+	img := e.scaled
+	if e.Bypass {
+		return img
+	}
+
+	for _, filter := range e.Filters {
+		img = filter.Apply(img, e.Scale)
+	}
+
+	return img
+}
+
+// Scale writes frame, 64x32 RGBA pixels, into dst with every pixel repeated
+// scale times in both directions.
+func Scale(dst *image.RGBA, frame []byte, scale int) {
+	for y := 0; y < HEIGHT*scale; y++ {
+		row := dst.Pix[y*dst.Stride:]
+		src := frame[(y/scale)*WIDTH*4:]
+
+		for x := 0; x < WIDTH*scale; x++ {
+			copy(row[x*4:x*4+4], src[(x/scale)*4:])
+		}
+	}
+}
+
+func darken(pix []byte, amount float32) {
+	for i := 0; i < 3; i++ {
+		pix[i] = uint8(float32(pix[i]) * (1 - amount))
+	}
+}
+
+// Scanlines darkens the lower half of every pixel row, like the gaps between
+// the lines of a CRT.
+type Scanlines struct {
+	Strength float32
+}
+
+func (f Scanlines) Name() string {
+	return "scanlines"
+}
+
+func (f Scanlines) Apply(img *image.RGBA, scale int) *image.RGBA {
+	if scale < 2 {
+		return img
+	}
+
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		if y%scale < (scale+1)/2 {
+			continue
+		}
+
+		row := img.Pix[y*img.Stride:]
+		for x := 0; x < b.Dx(); x++ {
+			darken(row[x*4:], f.Strength)
+		}
+	}
+
+	return img
+}
+
+// Grid darkens the right and bottom edge of every pixel, like an LCD.
+type Grid struct {
+	Strength float32
+}
+
+func (f Grid) Name() string {
+	return "grid"
+}
+
+func (f Grid) Apply(img *image.RGBA, scale int) *image.RGBA {
+	if scale < 3 {
+		return img
+	}
+
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := img.Pix[y*img.Stride:]
+		for x := 0; x < b.Dx(); x++ {
+			if x%scale == scale-1 || y%scale == scale-1 {
+				darken(row[x*4:], f.Strength)
+			}
+		}
+	}
+
+	return img
+}
+
+// Bloom approximates the glow around bright pixels by adding a blurred copy
+// of the frame on top of it.
+type Bloom struct {
+	Strength float32
+}
+
+func (f Bloom) Name() string {
+	return "bloom"
+}
+
+func (f Bloom) Apply(img *image.RGBA, scale int) *image.RGBA {
+	radius := scale
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+
+	blurred := make([]float32, w*h*3)
+	tmp := make([]float32, w*h*3)
+	for i := 0; i < w*h; i++ {
+		for ch := 0; ch < 3; ch++ {
+			blurred[i*3+ch] = float32(img.Pix[(i/w)*img.Stride+(i%w)*4+ch])
+		}
+	}
+
+	// Two passes of a separable box blur look close enough to a gaussian
+	for pass := 0; pass < 2; pass++ {
+		boxBlur(tmp, blurred, w, h, radius, 1, w)
+		boxBlur(blurred, tmp, h, w, radius, w, 1)
+	}
+
+	for i := 0; i < w*h; i++ {
+		pix := img.Pix[(i/w)*img.Stride+(i%w)*4:]
+		for ch := 0; ch < 3; ch++ {
+			// Screen blend, so the glow never clips harshly
+			base := float32(pix[ch]) / 255
+			glow := blurred[i*3+ch] / 255 * f.Strength
+			pix[ch] = uint8((1 - (1-base)*(1-glow)) * 255)
+		}
+	}
+
+	return img
+}
+
+// boxBlur averages src into dst along lines of length n. step is the distance
+// between neighbours on a line and stride the distance between lines, both in
+// pixels.
+func boxBlur(dst, src []float32, n, lines, radius, step, stride int) {
+	size := float32(2*radius + 1)
+
+	for line := 0; line < lines; line++ {
+		base := line * stride
+
+		for ch := 0; ch < 3; ch++ {
+			at := func(i int) float32 {
+				i = min(max(i, 0), n-1)
+				return src[(base+i*step)*3+ch]
+			}
+
+			var sum float32
+			for i := -radius; i <= radius; i++ {
+				sum += at(i)
+			}
+
+			for i := 0; i < n; i++ {
+				dst[(base+i*step)*3+ch] = sum / size
+				sum += at(i+radius+1) - at(i-radius)
+			}
+		}
+	}
+}
+
+// Curvature bends the frame like the glass of a CRT tube. Corners pushed
+// outside the frame are left black.
+type Curvature struct {
+	Amount float64
+}
+
+func (f Curvature) Name() string {
+	return "curvature"
+}
+
+func (f Curvature) Apply(img *image.RGBA, scale int) *image.RGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	out := image.NewRGBA(img.Rect)
+
+	for y := 0; y < h; y++ {
+		ny := 2*(float64(y)+0.5)/float64(h) - 1
+
+		for x := 0; x < w; x++ {
+			nx := 2*(float64(x)+0.5)/float64(w) - 1
+
+			// Barrel distortion, sampling further out towards the edges
+			r2 := nx*nx + ny*ny
+			sx := nx * (1 + f.Amount*r2)
+			sy := ny * (1 + f.Amount*r2)
+			if math.Abs(sx) > 1 || math.Abs(sy) > 1 {
+				out.Pix[y*out.Stride+x*4+3] = 0xFF
+				continue
+			}
+
+			px := int((sx + 1) / 2 * float64(w))
+			py := int((sy + 1) / 2 * float64(h))
+			px = min(px, w-1)
+			py = min(py, h-1)
+
+			copy(out.Pix[y*out.Stride+x*4:y*out.Stride+x*4+4], img.Pix[py*img.Stride+px*4:])
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,34 @@
+package render
+
+import "testing"
+
+func TestEffects(t *testing.T) {
+	var video [WIDTH * HEIGHT]uint32
+	video[0] = 1
+
+	frame := make([]byte, WIDTH*HEIGHT*4)
+	DefaultPalette.Fill(&video, frame)
+
+	effects, err := ParseEffects("scanlines", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := effects.Apply(frame)
+
+	if w, h := effects.Size(); img.Rect.Dx() != w || img.Rect.Dy() != h {
+		t.Fatalf("Unexpected size %v", img.Rect)
+	}
+
+	if img.RGBAAt(3, 1).R != 0xFF || img.RGBAAt(4, 0).R != 0 {
+		t.Errorf("Expected pixel upscaled to a 4x4 block")
+	}
+
+	if got := img.RGBAAt(0, 3).R; got >= 0xFF {
+		t.Errorf("Expected lower half of the pixel row darkened, got %d", got)
+	}
+
+	if _, err := ParseEffects("scanlines,vhs", 4); err == nil {
+		t.Errorf("Expected error for unknown filter")
+	}
+}
@@ -0,0 +1,113 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"os"
+	"time"
+)
+
+const FRAMES_PER_SECOND = 60
+
+// Recorder captures emulator frames into an animated GIF. GIF frame delays
+// are in hundredths of a second, so the 60 Hz timing is kept by carrying the
+// rounding over to the next frame.
+type Recorder struct {
+	palette   Palette
+	scale     int
+	maxFrames int
+
+	gif        gif.GIF
+	frames     int // 60 Hz ticks recorded
+	lastCentis int // time of the last captured frame, 1/100 s
+}
+
+// NewRecorder returns a Recorder drawing frames with palette, scale x scale
+// pixels, that stops recording after limit. A zero limit records until Save.
+func NewRecorder(palette Palette, scale int, limit time.Duration) *Recorder {
+	if scale < 1 {
+		scale = 1
+	}
+
+	return &Recorder{
+		palette:   palette,
+		scale:     scale,
+		maxFrames: int(limit * FRAMES_PER_SECOND / time.Second),
+	}
+}
+
+// Tick records one 60 Hz frame. A GIF frame is only captured when the
+// display changed, otherwise the previous one is shown for longer.
+func (r *Recorder) Tick(video *[WIDTH * HEIGHT]uint32, changed bool) {
+	if r.Full() {
+		return
+	}
+
+	if changed || len(r.gif.Image) == 0 {
+		r.closeFrame()
+		r.gif.Image = append(r.gif.Image, r.paletted(video))
+		r.gif.Delay = append(r.gif.Delay, 0)
+	}
+
+	r.frames++
+}
+
+// Full reports whether the duration limit was reached.
+func (r *Recorder) Full() bool {
+	return r.maxFrames > 0 && r.frames >= r.maxFrames
+}
+
+// Duration returns the length of the recording so far.
+func (r *Recorder) Duration() time.Duration {
+	return time.Duration(r.frames) * time.Second / FRAMES_PER_SECOND
+}
+
+// closeFrame sets the delay of the last captured frame to the time since it
+// was captured.
+func (r *Recorder) closeFrame() {
+	if len(r.gif.Image) == 0 {
+		return
+	}
+
+	centis := r.frames * 100 / FRAMES_PER_SECOND
+	r.gif.Delay[len(r.gif.Delay)-1] = max(centis-r.lastCentis, 1)
+	r.lastCentis = centis
+}
+
+func (r *Recorder) paletted(video *[WIDTH * HEIGHT]uint32) *image.Paletted {
+	colors := make(color.Palette, len(r.palette.Colors))
+	for i, c := range r.palette.Colors {
+		colors[i] = c
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, WIDTH*r.scale, HEIGHT*r.scale), colors)
+	for y := 0; y < HEIGHT*r.scale; y++ {
+		row := img.Pix[y*img.Stride:]
+		for x := 0; x < WIDTH*r.scale; x++ {
+			row[x] = uint8(video[(y/r.scale)*WIDTH+x/r.scale] & 3)
+		}
+	}
+
+	return img
+}
+
+func (r *Recorder) Encode(w io.Writer) error {
+	r.closeFrame()
+	return gif.EncodeAll(w, &r.gif)
+}
+
+func (r *Recorder) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := r.Encode(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,40 @@
+package render
+
+import (
+	"bytes"
+	"image/gif"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder(DefaultPalette, 2, time.Second)
+
+	var video [WIDTH * HEIGHT]uint32
+	for i := 0; i < 2*FRAMES_PER_SECOND; i++ {
+		video[0] = uint32(i/30) & 1
+		r.Tick(&video, i%30 == 0)
+	}
+
+	if !r.Full() || r.Duration() != time.Second {
+		t.Errorf("Expected recording to stop after 1s, got %v", r.Duration())
+	}
+
+	var buf bytes.Buffer
+	if err := r.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.Image) != 2 || g.Delay[0] != 50 || g.Delay[1] != 50 {
+		t.Errorf("Expected 2 frames of 0.5s, got %d frames, delays %v", len(g.Image), g.Delay)
+	}
+
+	if g.Image[0].Bounds().Dx() != WIDTH*2 {
+		t.Errorf("Expected frames scaled by 2")
+	}
+}
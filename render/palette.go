@@ -0,0 +1,114 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+const WIDTH = 64
+const HEIGHT = 32
+
+/*
+Palette colours a Chip8.Video buffer. Pixel values index Colors, so plain
+CHIP-8 only uses the background (0) and foreground (1). The other two are for
+XO-CHIP's second bitplane (2) and pixels set on both planes (3).
+*/
+type Palette struct {
+	Name   string
+	Colors [4]color.RGBA
+}
+
+func rgb(v uint32) color.RGBA {
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xFF}
+}
+
+// Palettes are the named presets, in the order the palette hotkey cycles them
+var Palettes = []Palette{
+	{Name: "classic", Colors: [4]color.RGBA{rgb(0x000000), rgb(0xFFFFFF), rgb(0xAAAAAA), rgb(0x555555)}},
+	{Name: "green", Colors: [4]color.RGBA{rgb(0x0A1A0A), rgb(0x33FF66), rgb(0x1E9E3E), rgb(0xA8FFBF)}},
+	{Name: "amber", Colors: [4]color.RGBA{rgb(0x1A0F00), rgb(0xFFB000), rgb(0x9E6B00), rgb(0xFFDD88)}},
+	{Name: "lcd", Colors: [4]color.RGBA{rgb(0x9BBC0F), rgb(0x0F380F), rgb(0x306230), rgb(0x8BAC0F)}},
+	{Name: "high-contrast", Colors: [4]color.RGBA{rgb(0x000000), rgb(0xFFFF00), rgb(0x00FFFF), rgb(0xFF00FF)}},
+}
+
+var DefaultPalette = Palettes[0]
+
+/*
+ParsePalette accepts a preset name or custom colours as 2 or 4 comma separated
+hex values, background first:
+
+	#000000,#33ff66
+	#000000,#ffffff,#ff0000,#0000ff
+
+With only two colours the XO-CHIP colours are taken from the classic preset.
+*/
+func ParsePalette(s string) (Palette, error) {
+	for _, p := range Palettes {
+		if strings.EqualFold(p.Name, s) {
+			return p, nil
+		}
+	}
+
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 && len(parts) != 4 {
+		return Palette{}, fmt.Errorf("unknown palette %q", s)
+	}
+
+	palette := Palette{Name: "custom", Colors: DefaultPalette.Colors}
+	for i, part := range parts {
+		c, err := parseColor(part)
+		if err != nil {
+			return Palette{}, err
+		}
+		palette.Colors[i] = c
+	}
+
+	return palette, nil
+}
+
+func parseColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil || len(s) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid colour %q, expected #rrggbb", s)
+	}
+
+	return rgb(uint32(v)), nil
+}
+
+// Next returns the preset after p, wrapping around. Custom palettes are
+// followed by the first preset.
+func (p Palette) Next() Palette {
+	for i, preset := range Palettes {
+		if preset.Name == p.Name {
+			return Palettes[(i+1)%len(Palettes)]
+		}
+	}
+	return Palettes[0]
+}
+
+func (p Palette) Color(v uint32) color.RGBA {
+	return p.Colors[v&3]
+}
+
+// Fill writes video into dst as 64x32 RGBA pixels, 4 bytes each.
+func (p Palette) Fill(video *[WIDTH * HEIGHT]uint32, dst []byte) {
+	for i, v := range video {
+		c := p.Color(v)
+		dst[i*4] = c.R
+		dst[i*4+1] = c.G
+		dst[i*4+2] = c.B
+		dst[i*4+3] = c.A
+	}
+}
+
+// Image returns video as a 64x32 image.
+func (p Palette) Image(video *[WIDTH * HEIGHT]uint32) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, WIDTH, HEIGHT))
+	p.Fill(video, img.Pix)
+	return img
+}
@@ -0,0 +1,36 @@
+package render
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParsePalette(t *testing.T) {
+	p, err := ParsePalette("Amber")
+	if err != nil || p.Name != "amber" {
+		t.Errorf("Expected amber preset, got %v, %v", p.Name, err)
+	}
+
+	p, err = ParsePalette("#102030,#FFFFFF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Colors[0] != (color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xFF}) || p.Colors[2] != DefaultPalette.Colors[2] {
+		t.Errorf("Unexpected custom palette %v", p.Colors)
+	}
+
+	if _, err := ParsePalette("#12345"); err == nil {
+		t.Errorf("Expected error for invalid palette")
+	}
+}
+
+func TestImage(t *testing.T) {
+	var video [WIDTH * HEIGHT]uint32
+	video[WIDTH+2] = 1
+
+	img := Palettes[1].Image(&video)
+
+	if img.RGBAAt(2, 1) != Palettes[1].Colors[1] || img.RGBAAt(0, 0) != Palettes[1].Colors[0] {
+		t.Errorf("Pixels not coloured by palette")
+	}
+}
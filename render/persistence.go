@@ -0,0 +1,160 @@
+package render
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type PersistenceMode uint8
+
+const (
+	PERSISTENCE_OFF   PersistenceMode = iota
+	PERSISTENCE_DECAY                 // erased pixels fade out like phosphor
+	PERSISTENCE_BLEND                 // average of the last frames
+)
+
+const DEFAULT_DECAY = 0.6
+const DEFAULT_BLEND_FRAMES = 2
+
+/*
+Persistence is the post-processing stage that turns the display buffer into
+RGBA. CHIP-8 games erase and redraw sprites with XOR, so a sprite is often
+missing from every other frame and flickers. With PERSISTENCE_DECAY erased
+pixels fade out over a few frames instead of disappearing, with
+PERSISTENCE_BLEND every frame is the average of the last Frames frames.
+
+Frames are added once per emulated frame (RunFrame), so the effect doesn't
+depend on how often the frontend presents them.
+*/
+type Persistence struct {
+	Mode   PersistenceMode
+	Decay  float32 // Brightness an erased pixel keeps per frame (0-1)
+	Frames int     // Frames averaged by PERSISTENCE_BLEND
+
+	glow    [WIDTH * HEIGHT][3]float32
+	history [][WIDTH * HEIGHT]uint32
+	next    int
+	palette Palette
+	out     [WIDTH * HEIGHT * 4]byte
+}
+
+func NewPersistence(mode PersistenceMode) *Persistence {
+	return &Persistence{Mode: mode, Decay: DEFAULT_DECAY, Frames: DEFAULT_BLEND_FRAMES}
+}
+
+/*
+ParsePersistence accepts "off", "decay" or "blend", optionally followed by the
+amount after a colon:
+
+	decay:0.8   erased pixels keep 80% of their brightness each frame
+	blend:3     average the last 3 frames
+*/
+func ParsePersistence(s string) (*Persistence, error) {
+	name, amount, hasAmount := strings.Cut(s, ":")
+
+	switch name {
+	case "off", "":
+		return NewPersistence(PERSISTENCE_OFF), nil
+	case "decay":
+		p := NewPersistence(PERSISTENCE_DECAY)
+		if hasAmount {
+			decay, err := strconv.ParseFloat(amount, 32)
+			if err != nil || decay < 0 || decay >= 1 {
+				return nil, fmt.Errorf("invalid decay %q, expected a number from 0 to 1", amount)
+			}
+			p.Decay = float32(decay)
+		}
+		return p, nil
+	case "blend":
+		p := NewPersistence(PERSISTENCE_BLEND)
+		if hasAmount {
+			frames, err := strconv.Atoi(amount)
+			if err != nil || frames < 1 {
+				return nil, fmt.Errorf("invalid blend %q, expected a number of frames", amount)
+			}
+			p.Frames = frames
+		}
+		return p, nil
+	}
+
+	return nil, fmt.Errorf("unknown persistence mode %q", s)
+}
+
+// Active reports whether frames must be added every emulated frame, rather
+// than only when the display changed.
+func (p *Persistence) Active() bool {
+	return p.Mode != PERSISTENCE_OFF
+}
+
+// Add records one emulated frame coloured with palette.
+func (p *Persistence) Add(video *[WIDTH * HEIGHT]uint32, palette Palette) {
+	switch p.Mode {
+	case PERSISTENCE_DECAY:
+		p.addDecay(video, palette)
+	case PERSISTENCE_BLEND:
+		p.addBlend(video, palette)
+	default:
+		palette.Fill(video, p.out[:])
+	}
+	p.palette = palette
+}
+
+func (p *Persistence) addDecay(video *[WIDTH * HEIGHT]uint32, palette Palette) {
+	// Start from the background after a palette change
+	if palette != p.palette {
+		for i, v := range video {
+			c := palette.Color(v)
+			p.glow[i] = [3]float32{float32(c.R), float32(c.G), float32(c.B)}
+		}
+	}
+
+	for i, v := range video {
+		c := palette.Color(v)
+		target := [3]float32{float32(c.R), float32(c.G), float32(c.B)}
+
+		for ch := range target {
+			if v != 0 {
+				p.glow[i][ch] = target[ch]
+			} else {
+				p.glow[i][ch] = target[ch] + (p.glow[i][ch]-target[ch])*p.Decay
+			}
+			p.out[i*4+ch] = uint8(p.glow[i][ch] + 0.5)
+		}
+		p.out[i*4+3] = 0xFF
+	}
+}
+
+func (p *Persistence) addBlend(video *[WIDTH * HEIGHT]uint32, palette Palette) {
+	if len(p.history) != p.Frames {
+		p.history = make([][WIDTH * HEIGHT]uint32, p.Frames)
+		for i := range p.history {
+			p.history[i] = *video
+		}
+		p.next = 0
+	}
+
+	p.history[p.next] = *video
+	p.next = (p.next + 1) % len(p.history)
+
+	n := len(p.history)
+	for i := range video {
+		var sum [3]int
+		for f := range p.history {
+			c := palette.Color(p.history[f][i])
+			sum[0] += int(c.R)
+			sum[1] += int(c.G)
+			sum[2] += int(c.B)
+		}
+
+		for ch := range sum {
+			p.out[i*4+ch] = uint8((sum[ch] + n/2) / n)
+		}
+		p.out[i*4+3] = 0xFF
+	}
+}
+
+// Fill writes the last added frame into dst as 64x32 RGBA pixels.
+func (p *Persistence) Fill(dst []byte) {
+	copy(dst, p.out[:])
+}
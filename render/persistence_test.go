@@ -0,0 +1,44 @@
+package render
+
+import "testing"
+
+func TestPersistenceDecay(t *testing.T) {
+	p := NewPersistence(PERSISTENCE_DECAY)
+	p.Decay = 0.5
+
+	var video [WIDTH * HEIGHT]uint32
+	p.Add(&video, DefaultPalette)
+
+	video[0] = 1
+	p.Add(&video, DefaultPalette)
+
+	video[0] = 0
+	p.Add(&video, DefaultPalette)
+
+	var frame [WIDTH * HEIGHT * 4]byte
+	p.Fill(frame[:])
+
+	if frame[0] != 128 || frame[3] != 0xFF {
+		t.Errorf("Expected erased pixel at half brightness, got %d", frame[0])
+	}
+}
+
+func TestPersistenceBlend(t *testing.T) {
+	p, err := ParsePersistence("blend:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var video [WIDTH * HEIGHT]uint32
+	p.Add(&video, DefaultPalette)
+
+	video[1] = 1
+	p.Add(&video, DefaultPalette)
+
+	var frame [WIDTH * HEIGHT * 4]byte
+	p.Fill(frame[:])
+
+	if frame[4] != 128 || frame[0] != 0 {
+		t.Errorf("Expected pixel averaged over 2 frames, got %d", frame[4])
+	}
+}
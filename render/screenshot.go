@@ -0,0 +1,42 @@
+package render
+
+import (
+	"image"
+	"image/png"
+	"io"
+	"os"
+)
+
+// Screenshot returns video coloured with palette, with every pixel drawn as a
+// scale x scale block.
+func Screenshot(video *[WIDTH * HEIGHT]uint32, palette Palette, scale int) *image.RGBA {
+	if scale < 1 {
+		scale = 1
+	}
+
+	frame := make([]byte, WIDTH*HEIGHT*4)
+	palette.Fill(video, frame)
+
+	img := image.NewRGBA(image.Rect(0, 0, WIDTH*scale, HEIGHT*scale))
+	Scale(img, frame, scale)
+
+	return img
+}
+
+func WritePNG(w io.Writer, img image.Image) error {
+	return png.Encode(w, img)
+}
+
+func SavePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := WritePNG(f, img); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,30 @@
+package render
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+)
+
+func TestScreenshotPNG(t *testing.T) {
+	var video [WIDTH * HEIGHT]uint32
+	video[WIDTH*HEIGHT-1] = 1
+
+	var buf bytes.Buffer
+	if err := WritePNG(&buf, Screenshot(&video, DefaultPalette, 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b := img.Bounds(); b.Dx() != WIDTH*2 || b.Dy() != HEIGHT*2 {
+		t.Fatalf("Unexpected size %v", b)
+	}
+
+	if r, _, _, _ := img.At(WIDTH*2-1, HEIGHT*2-1).RGBA(); r != 0xFFFF {
+		t.Errorf("Expected last pixel lit")
+	}
+}
@@ -0,0 +1,201 @@
+package romdb
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"github.com/brunocroh/chip8/cpu"
+	"github.com/brunocroh/chip8/render"
+	"strings"
+	"sync"
+)
+
+/*
+The database uses the layout of the community CHIP-8 database
+(https://github.com/chip-8/chip-8-database): programs.json lists the
+programs with their ROMs keyed by SHA-1, and sha1-hashes.json maps every
+hash to its program's index. The copy in the repository is refreshed with
+`make romdb`.
+*/
+//go:embed database/programs.json database/sha1-hashes.json
+var files embed.FS
+
+type Program struct {
+	Title       string          `json:"title"`
+	Description string          `json:"description,omitempty"`
+	Release     string          `json:"release,omitempty"`
+	Authors     []string        `json:"authors,omitempty"`
+	Roms        map[string]*Rom `json:"roms"`
+}
+
+type Rom struct {
+	File            string                     `json:"file,omitempty"`
+	EmbeddedTitle   string                     `json:"embeddedTitle,omitempty"`
+	Platforms       []string                   `json:"platforms"`
+	QuirkyPlatforms map[string]map[string]bool `json:"quirkyPlatforms,omitempty"`
+	Tickrate        int                        `json:"tickrate,omitempty"` // Instructions per frame
+	Keys            map[string]uint8           `json:"keys,omitempty"`     // Keypad keys used, by role (up, a, player2Up, ...)
+	Colors          *Colors                    `json:"colors,omitempty"`
+}
+
+type Colors struct {
+	Pixels  []string `json:"pixels,omitempty"` // Background first
+	Buzzer  string   `json:"buzzer,omitempty"`
+	Silence string   `json:"silence,omitempty"`
+}
+
+// Info describes one ROM of a program.
+type Info struct {
+	*Program
+	*Rom
+	SHA1 string
+}
+
+type Database struct {
+	programs []*Program
+	hashes   map[string]int
+}
+
+func Parse(programs, hashes []byte) (*Database, error) {
+	db := &Database{}
+	if err := json.Unmarshal(programs, &db.programs); err != nil {
+		return nil, fmt.Errorf("romdb: programs: %w", err)
+	}
+	if err := json.Unmarshal(hashes, &db.hashes); err != nil {
+		return nil, fmt.Errorf("romdb: hashes: %w", err)
+	}
+
+	for hash, i := range db.hashes {
+		if i < 0 || i >= len(db.programs) {
+			return nil, fmt.Errorf("romdb: hash %s points to missing program %d", hash, i)
+		}
+	}
+
+	return db, nil
+}
+
+// Lookup returns the ROM with the hex encoded SHA-1 hash.
+func (db *Database) Lookup(sha1 string) (*Info, bool) {
+	sha1 = strings.ToLower(sha1)
+
+	i, ok := db.hashes[sha1]
+	if !ok {
+		return nil, false
+	}
+
+	program := db.programs[i]
+	rom, ok := program.Roms[sha1]
+	if !ok {
+		return nil, false
+	}
+
+	return &Info{Program: program, Rom: rom, SHA1: sha1}, true
+}
+
+var embedded struct {
+	once sync.Once
+	db   *Database
+	err  error
+}
+
+// Embedded returns the database built into the binary.
+func Embedded() (*Database, error) {
+	embedded.once.Do(func() {
+		programs, err := files.ReadFile("database/programs.json")
+		if err != nil {
+			embedded.err = err
+			return
+		}
+
+		hashes, err := files.ReadFile("database/sha1-hashes.json")
+		if err != nil {
+			embedded.err = err
+			return
+		}
+
+		embedded.db, embedded.err = Parse(programs, hashes)
+	})
+
+	return embedded.db, embedded.err
+}
+
+// Lookup finds sha1 in the embedded database.
+func Lookup(sha1 string) (*Info, bool) {
+	db, err := Embedded()
+	if err != nil {
+		return nil, false
+	}
+	return db.Lookup(sha1)
+}
+
+// Database platform ids, see platforms.json of the CHIP-8 database
+var platforms = map[string]cpu.Platform{
+	"originalChip8": cpu.PLATFORM_VIP,
+	"hybridVIP":     cpu.PLATFORM_VIP,
+	"modernChip8":   cpu.PLATFORM_CHIP8,
+	"chip48":        cpu.PLATFORM_SCHIP,
+	"superchip1":    cpu.PLATFORM_SCHIP,
+	"superchip":     cpu.PLATFORM_SCHIP,
+	"xochip":        cpu.PLATFORM_XOCHIP,
+}
+
+// Platform returns the first of the ROM's platforms the emulator knows, in
+// the database order of preference, and its id.
+func (i *Info) Platform() (cpu.Platform, string, bool) {
+	for _, id := range i.Platforms {
+		if p, ok := platforms[id]; ok {
+			return p, id, true
+		}
+	}
+	return cpu.PLATFORM_CHIP8, "", false
+}
+
+// Quirks returns the quirks of the ROM's platform, with the overrides the
+// database lists for it.
+func (i *Info) Quirks() cpu.Quirks {
+	platform, id, _ := i.Platform()
+	q := platform.Quirks()
+
+	for name, on := range i.QuirkyPlatforms[id] {
+		switch name {
+		case "shift":
+			q.ShiftVy = !on
+		case "memoryLeaveIUnchanged":
+			q.KeepIndex = on
+		case "jump":
+			q.JumpVx = on
+		case "logic":
+			q.VFReset = on
+		case "wrap":
+			q.Clip = !on
+		case "vblank":
+			q.VBlankWait = on
+		}
+	}
+
+	return q
+}
+
+// Palette returns the ROM's colours, when it has valid ones.
+func (i *Info) Palette() (render.Palette, bool) {
+	if i.Colors == nil {
+		return render.Palette{}, false
+	}
+
+	palette, err := render.ParsePalette(strings.Join(i.Colors.Pixels, ","))
+	if err != nil {
+		return render.Palette{}, false
+	}
+
+	palette.Name = "rom"
+	return palette, true
+}
+
+// Name returns the title of the program, with the ROM's file when the
+// program has several.
+func (i *Info) Name() string {
+	if len(i.Program.Roms) > 1 && i.File != "" {
+		return fmt.Sprintf("%s (%s)", i.Title, i.File)
+	}
+	return i.Title
+}
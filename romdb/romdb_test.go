@@ -0,0 +1,98 @@
+package romdb
+
+import (
+	"github.com/brunocroh/chip8/cpu"
+	"strings"
+	"testing"
+)
+
+const programs = `[
+  {
+    "title": "Test Game",
+    "authors": ["Someone"],
+    "roms": {
+      "aaaa": {
+        "file": "test.ch8",
+        "platforms": ["megachip8", "superchip"],
+        "quirkyPlatforms": { "superchip": { "shift": false, "wrap": true } },
+        "tickrate": 30,
+        "keys": { "up": 5, "a": 6 },
+        "colors": { "pixels": ["#000000", "#ff8000"] }
+      }
+    }
+  }
+]`
+
+const hashes = `{ "aaaa": 0 }`
+
+func TestLookup(t *testing.T) {
+	db, err := Parse([]byte(programs), []byte(hashes))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, ok := db.Lookup("AAAA")
+	if !ok {
+		t.Fatal("Expected aaaa to be found")
+	}
+
+	if info.Title != "Test Game" || info.Tickrate != 30 || info.Keys["up"] != 5 {
+		t.Errorf("Unexpected info %+v %+v", info.Program, info.Rom)
+	}
+
+	if platform, _, _ := info.Platform(); platform != cpu.PLATFORM_SCHIP {
+		t.Errorf("Expected schip, got %v", platform)
+	}
+
+	want := cpu.PLATFORM_SCHIP.Quirks()
+	want.ShiftVy, want.Clip = true, false
+	if q := info.Quirks(); q != want {
+		t.Errorf("Quirks = %+v, want %+v", q, want)
+	}
+
+	if palette, ok := info.Palette(); !ok || palette.Colors[1].R != 0xFF {
+		t.Errorf("Unexpected palette %+v", palette)
+	}
+
+	if _, ok := db.Lookup("bbbb"); ok {
+		t.Error("Expected bbbb to be missing")
+	}
+}
+
+func TestParseBadIndex(t *testing.T) {
+	if _, err := Parse([]byte(programs), []byte(`{ "bbbb": 3 }`)); err == nil {
+		t.Error("Expected an error for a hash pointing past the programs")
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+	if _, err := Embedded(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestEmbeddedLookup looks up every hash of the embedded database, which
+// the snapshot from make romdb has to resolve to a ROM with keypad keys
+func TestEmbeddedLookup(t *testing.T) {
+	db, err := Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(db.hashes) == 0 {
+		t.Skip("the embedded database is empty, run make romdb")
+	}
+
+	for hash := range db.hashes {
+		info, ok := Lookup(strings.ToUpper(hash))
+		if !ok {
+			t.Errorf("Expected %s to resolve to a ROM of program %d", hash, db.hashes[hash])
+			continue
+		}
+
+		for role, key := range info.Keys {
+			if key > 0xF {
+				t.Errorf("%s (%s): key %s is %#x, not a keypad key", info.Name(), hash, role, key)
+			}
+		}
+	}
+}
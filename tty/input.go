@@ -0,0 +1,126 @@
+package tty
+
+import (
+	"time"
+	"unicode/utf8"
+)
+
+/*
+Terminals only send characters, repeated while a key is held, and never
+report releases. A key counts as released once KeyHold passes without it
+being sent again. The first repeat usually comes after about half a second,
+so keys held longer than KeyHold are briefly released once.
+*/
+const DEFAULT_KEY_HOLD = 150 * time.Millisecond
+
+type KeyEvent struct {
+	Name    string // As used by keymap.Normalize
+	Pressed bool
+}
+
+// Escape sequences for keys that don't send a single character
+var sequences = map[string]string{
+	"\x1b[A":   "arrowup",
+	"\x1b[B":   "arrowdown",
+	"\x1b[C":   "arrowright",
+	"\x1b[D":   "arrowleft",
+	"\x1bOA":   "arrowup",
+	"\x1bOB":   "arrowdown",
+	"\x1bOC":   "arrowright",
+	"\x1bOD":   "arrowleft",
+	"\x1bOQ":   "f2",
+	"\x1bOR":   "f3",
+	"\x1b[12~": "f2",
+	"\x1b[13~": "f3",
+	"\x1b[21~": "f10",
+	"\x1b[23~": "f11",
+	"\x1b[24~": "f12",
+}
+
+func (t *Terminal) readKeys() {
+	buf := make([]byte, 64)
+	for {
+		n, err := t.in.Read(buf)
+		if err != nil {
+			close(t.keys)
+			return
+		}
+
+		for _, name := range parseKeys(buf[:n]) {
+			t.keys <- name
+		}
+	}
+}
+
+func parseKeys(data []byte) []string {
+	var names []string
+
+	for len(data) > 0 {
+		if data[0] == 0x1b {
+			if name, n := sequence(data); n > 0 {
+				names = append(names, name)
+				data = data[n:]
+				continue
+			}
+		}
+
+		// Keys like é on AZERTY layouts take several bytes
+		r, n := utf8.DecodeRune(data)
+		switch r {
+		case 0x1b:
+			names = append(names, "escape")
+		case '\r', '\n':
+			names = append(names, "enter")
+		case 0x03:
+			names = append(names, "ctrl+c")
+		default:
+			names = append(names, string(r))
+		}
+		data = data[n:]
+	}
+
+	return names
+}
+
+// sequence matches the escape sequence data starts with, returning its key
+// name and length.
+func sequence(data []byte) (string, int) {
+	for n := 3; n <= 5 && n <= len(data); n++ {
+		if name, ok := sequences[string(data[:n])]; ok {
+			return name, n
+		}
+	}
+	return "", 0
+}
+
+// Poll returns the key presses read since the last call and the releases
+// simulated with KeyHold. quit is true after Ctrl+C or when stdin closed.
+func (t *Terminal) Poll() (events []KeyEvent, quit bool) {
+	now := time.Now()
+
+read:
+	for {
+		select {
+		case name, ok := <-t.keys:
+			if !ok || name == "ctrl+c" {
+				return events, true
+			}
+
+			if _, held := t.held[name]; !held {
+				events = append(events, KeyEvent{Name: name, Pressed: true})
+			}
+			t.held[name] = now.Add(t.KeyHold)
+		default:
+			break read
+		}
+	}
+
+	for name, deadline := range t.held {
+		if now.After(deadline) {
+			events = append(events, KeyEvent{Name: name, Pressed: false})
+			delete(t.held, name)
+		}
+	}
+
+	return events, false
+}
@@ -0,0 +1,15 @@
+package tty
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	got := parseKeys([]byte("q\x1b[A\r\x1bw\x1b[24~\x1bOQé\x03"))
+	want := []string{"q", "arrowup", "enter", "escape", "w", "f12", "f2", "é", "ctrl+c"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseKeys = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,194 @@
+package tty
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/brunocroh/chip8/render"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+type Mode uint8
+
+const (
+	MODE_HALFBLOCK Mode = iota // one cell per 1x2 pixels, full colour
+	MODE_BRAILLE               // one cell per 2x4 pixels, for small terminals
+)
+
+func ParseMode(name string) (Mode, bool) {
+	switch name {
+	case "halfblock":
+		return MODE_HALFBLOCK, true
+	case "braille":
+		return MODE_BRAILLE, true
+	}
+	return MODE_HALFBLOCK, false
+}
+
+// Terminal draws the display with ANSI escape codes and reads keys from
+// stdin in raw mode. Raw mode is set with stty, so no cgo is needed.
+type Terminal struct {
+	Mode    Mode
+	KeyHold time.Duration
+
+	in    *os.File
+	out   *bufio.Writer
+	saved string
+	last  string
+	keys  chan string
+	held  map[string]time.Time // Release deadline of held keys
+}
+
+func Open(mode Mode) (*Terminal, error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("stdin is not a terminal: %w", err)
+	}
+
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+
+	t := &Terminal{
+		Mode:    mode,
+		KeyHold: DEFAULT_KEY_HOLD,
+		in:      os.Stdin,
+		out:     bufio.NewWriter(os.Stdout),
+		saved:   strings.TrimSpace(saved),
+		keys:    make(chan string, 64),
+		held:    map[string]time.Time{},
+	}
+	go t.readKeys()
+
+	// Alternate screen, hidden cursor, cleared
+	t.out.WriteString("\x1b[?1049h\x1b[?25l\x1b[2J")
+	t.out.Flush()
+
+	return t, nil
+}
+
+// Close restores the terminal to the state it was opened in.
+func (t *Terminal) Close() error {
+	t.out.WriteString("\x1b[0m\x1b[?25h\x1b[?1049l")
+	t.out.Flush()
+
+	_, err := stty(t.saved)
+	return err
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// Bell rings the terminal bell, the closest thing to sound a terminal has.
+func (t *Terminal) Bell() {
+	t.out.WriteString("\a")
+	t.out.Flush()
+}
+
+// Status writes message on the line below the display, where it stays
+// until the next one.
+func (t *Terminal) Status(message string) {
+	rows := render.HEIGHT / 2
+	if t.Mode == MODE_BRAILLE {
+		rows = render.HEIGHT / 4
+	}
+
+	fmt.Fprintf(t.out, "\x1b[%d;1H\x1b[0m\x1b[2K%s", rows+1, message)
+	t.out.Flush()
+}
+
+// Draw renders frame, 64x32 RGBA pixels, at the top left of the terminal.
+// Identical frames are not written again.
+func (t *Terminal) Draw(frame []byte, palette render.Palette) error {
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+
+	switch t.Mode {
+	case MODE_BRAILLE:
+		drawBraille(&b, frame, palette)
+	default:
+		drawHalfBlock(&b, frame)
+	}
+
+	screen := b.String()
+	if screen == t.last {
+		return nil
+	}
+	t.last = screen
+
+	if _, err := io.WriteString(t.out, screen); err != nil {
+		return err
+	}
+	return t.out.Flush()
+}
+
+func pixel(frame []byte, x, y int) [3]byte {
+	i := (y*render.WIDTH + x) * 4
+	return [3]byte{frame[i], frame[i+1], frame[i+2]}
+}
+
+// drawHalfBlock uses the upper half block with the top pixel as foreground
+// and the bottom one as background colour.
+func drawHalfBlock(b *strings.Builder, frame []byte) {
+	for y := 0; y < render.HEIGHT; y += 2 {
+		var fg, bg [3]byte
+		first := true
+
+		for x := 0; x < render.WIDTH; x++ {
+			top, bottom := pixel(frame, x, y), pixel(frame, x, y+1)
+
+			// Only send colours when they change, it is a lot less output
+			if first || top != fg {
+				fmt.Fprintf(b, "\x1b[38;2;%d;%d;%dm", top[0], top[1], top[2])
+				fg = top
+			}
+			if first || bottom != bg {
+				fmt.Fprintf(b, "\x1b[48;2;%d;%d;%dm", bottom[0], bottom[1], bottom[2])
+				bg = bottom
+			}
+			first = false
+
+			b.WriteString("▀")
+		}
+		b.WriteString("\x1b[0m\r\n")
+	}
+}
+
+// Dot bits of a braille character, indexed by [y][x] within the 2x4 cell
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// drawBraille draws every pixel that isn't background as a dot, in the
+// foreground colour over the background colour.
+func drawBraille(b *strings.Builder, frame []byte, palette render.Palette) {
+	fg, bg := palette.Color(1), palette.Color(0)
+	background := [3]byte{bg.R, bg.G, bg.B}
+
+	fmt.Fprintf(b, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm", fg.R, fg.G, fg.B, bg.R, bg.G, bg.B)
+	for y := 0; y < render.HEIGHT; y += 4 {
+		for x := 0; x < render.WIDTH; x += 2 {
+			cell := rune(0x2800)
+			for dy := 0; dy < 4; dy++ {
+				for dx := 0; dx < 2; dx++ {
+					if pixel(frame, x+dx, y+dy) != background {
+						cell |= brailleDots[dy][dx]
+					}
+				}
+			}
+			b.WriteRune(cell)
+		}
+		b.WriteString("\r\n")
+	}
+	b.WriteString("\x1b[0m")
+}
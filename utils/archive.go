@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// File extensions of ROMs, used to find them in archives
+var RomExtensions = []string{".ch8", ".sc8", ".xo8", ".c8"}
+
+// The largest file read from an archive, leaving room for ROMs written as
+// text and cartridge GIFs, whose size is checked once decoded
+const MAX_ENTRY_SIZE = 1 << 20
+
+// ArchiveError is returned when an archive holds several ROMs and none was
+// picked, or the picked one isn't there.
+type ArchiveError struct {
+	Archive    string
+	Entry      string
+	Candidates []string
+}
+
+func (e *ArchiveError) Error() string {
+	var b strings.Builder
+	if e.Entry == "" {
+		fmt.Fprintf(&b, "%s holds %d ROMs, pick one by name or number:", e.Archive, len(e.Candidates))
+	} else {
+		fmt.Fprintf(&b, "%s has no ROM %q, pick one by name or number:", e.Archive, e.Entry)
+	}
+
+	for i, name := range e.Candidates {
+		fmt.Fprintf(&b, "\n  %d  %s", i+1, name)
+	}
+	return b.String()
+}
+
+func isRom(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	for _, e := range RomExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// unpack returns the ROM in data when it is a zip or gzip archive, and its
+// name. Other data is returned as is.
+func unpack(data []byte, name, entry string) ([]byte, string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return unzip(data, name, entry)
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		return gunzip(data, name)
+	}
+	return data, name, nil
+}
+
+func gunzip(data []byte, name string) ([]byte, string, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", name, err)
+	}
+	defer r.Close()
+
+	rom, err := readRom(r, name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The original name is optional in gzip files
+	if r.Name != "" {
+		return rom, path.Base(r.Name), nil
+	}
+	return rom, strings.TrimSuffix(name, path.Ext(name)), nil
+}
+
+// unzip reads the ROM picked by entry, a name or a number starting at 1.
+// With no entry the archive must hold a single ROM. Files with ROM
+// extensions are the candidates, or every file when none has one.
+func unzip(data []byte, name, entry string) ([]byte, string, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", name, err)
+	}
+
+	var files, roms []*zip.File
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		files = append(files, f)
+		if isRom(f.Name) {
+			roms = append(roms, f)
+		}
+	}
+
+	candidates := roms
+	if len(candidates) == 0 {
+		candidates = files
+	}
+
+	if len(candidates) == 0 {
+		return nil, "", fmt.Errorf("%s: no files in the archive", name)
+	}
+
+	file := pick(candidates, entry)
+	if file == nil {
+		archiveErr := &ArchiveError{Archive: name, Entry: entry}
+		for _, f := range candidates {
+			archiveErr.Candidates = append(archiveErr.Candidates, f.Name)
+		}
+		return nil, "", archiveErr
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", file.Name, err)
+	}
+	defer rc.Close()
+
+	rom, err := readRom(rc, file.Name)
+	if err != nil {
+		return nil, "", err
+	}
+	return rom, path.Base(file.Name), nil
+}
+
+// pick finds entry among candidates by number, full name or file name,
+// ignoring case. An empty entry picks the only candidate.
+func pick(candidates []*zip.File, entry string) *zip.File {
+	if entry == "" {
+		if len(candidates) == 1 {
+			return candidates[0]
+		}
+		return nil
+	}
+
+	if i, err := strconv.Atoi(entry); err == nil {
+		if i >= 1 && i <= len(candidates) {
+			return candidates[i-1]
+		}
+		return nil
+	}
+
+	for _, f := range candidates {
+		if strings.EqualFold(f.Name, entry) || strings.EqualFold(path.Base(f.Name), entry) {
+			return f
+		}
+	}
+	return nil
+}
+
+// readRom reads a ROM from an archive, refusing anything larger than
+// MAX_ENTRY_SIZE so a compressed file can't blow up
+func readRom(r io.Reader, name string) ([]byte, error) {
+	rom, err := io.ReadAll(io.LimitReader(r, MAX_ENTRY_SIZE+1))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	if len(rom) > MAX_ENTRY_SIZE {
+		return nil, fmt.Errorf("%s: larger than %d bytes", name, MAX_ENTRY_SIZE)
+	}
+	return rom, nil
+}
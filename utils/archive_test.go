@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func zipFiles(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, data := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(data))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadRomZip(t *testing.T) {
+	data := zipFiles(t, map[string]string{
+		"readme.txt":     "hello",
+		"games/PONG.ch8": "pong",
+	})
+
+	rom, err := ReadRom(data, "games.zip", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rom.Name != "PONG.ch8" || string(rom.Data) != "pong" {
+		t.Errorf("Expected the only ROM, got %s %q", rom.Name, rom.Data)
+	}
+}
+
+func TestReadRomZipPick(t *testing.T) {
+	data := zipFiles(t, map[string]string{
+		"a.ch8": "a",
+		"b.sc8": "b",
+	})
+
+	var archiveErr *ArchiveError
+	if _, err := ReadRom(data, "games.zip", ""); !errors.As(err, &archiveErr) || len(archiveErr.Candidates) != 2 {
+		t.Fatalf("Expected the candidates to be listed, got %v", err)
+	}
+
+	rom, err := ReadRom(data, "games.zip", "B.SC8")
+	if err != nil || string(rom.Data) != "b" {
+		t.Errorf("Expected b by name, got %v %v", rom, err)
+	}
+
+	if _, err := ReadRom(data, "games.zip", "c.ch8"); !errors.As(err, &archiveErr) {
+		t.Errorf("Expected a missing entry error, got %v", err)
+	}
+
+	rom, err = ReadRom(data, "games.zip", "2")
+	if err != nil || rom.Name != archiveErr.Candidates[1] {
+		t.Errorf("Expected the second candidate by number, got %v %v", rom, err)
+	}
+}
+
+func TestReadRomGzip(t *testing.T) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte{0x00, 0xE0})
+	w.Close()
+
+	rom, err := ReadRom(buf.Bytes(), "maze.ch8.gz", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rom.Name != "maze.ch8" || !bytes.Equal(rom.Data, []byte{0x00, 0xE0}) {
+		t.Errorf("Unexpected ROM %s %x", rom.Name, rom.Data)
+	}
+}
+
+func TestReadRomPlain(t *testing.T) {
+	rom, err := ReadRom([]byte{0x12, 0x00}, "loop.ch8", "")
+	if err != nil || rom.Name != "loop.ch8" || len(rom.Data) != 2 {
+		t.Errorf("Expected the data as is, got %v %v", rom, err)
+	}
+}
+
+func TestReadRomZipHexText(t *testing.T) {
+	// Three characters a byte take more than the memory once unpacked
+	data := zipFiles(t, map[string]string{"game.txt": strings.Repeat("12 00 ", 770)})
+
+	rom, err := ReadRom(data, "game.zip", "")
+	if err != nil || len(rom.Data) != 1540 {
+		t.Errorf("Expected the hex dump to be decoded, got %v %v", rom, err)
+	}
+}
+
+func TestReadRomTooLarge(t *testing.T) {
+	if _, err := ReadRom(make([]byte, 4000), "large.ch8", ""); err == nil {
+		t.Error("Expected a ROM past the end of memory to be refused")
+	}
+
+	if _, err := ReadRom(make([]byte, MEMORY_SIZE-0x200), "full.ch8", ""); err != nil {
+		t.Errorf("Expected a ROM filling the memory, got %v", err)
+	}
+}
@@ -0,0 +1,726 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/brunocroh/chip8/cpu"
+	"strconv"
+	"strings"
+)
+
+/*
+AssembleOcto assembles the part of the Octo language cartridges are
+usually written in:
+
+	: main            labels, a bare label name calls it
+	  clear           and every other instruction: sprite, jump, :call,
+	  v0 := 0x12      return, save, load, bcd, hires, scroll-down, ...
+	  i := sprite
+	  loop
+	    if v0 != 3 begin v0 += 1 else v0 := 0 end
+	    while v1 key
+	  again
+
+	: sprite 0xFF 0x81 0b10000001 255
+
+with :const, :alias, :org, :byte, :pointer, :call and :unpack. Macros,
+:calc, :next, :stringmode, { expressions } and the <, >, <= and >=
+comparisons aren't supported and return ErrOctoSource.
+
+Execution starts at main: like Octo, a jump to it is put at 0x200 unless
+main is defined there.
+*/
+func AssembleOcto(source string) ([]byte, error) {
+	// Sizes don't depend on values, so a first pass finds the labels and a
+	// second assembles with them known
+	first := newOctoAssembler(source, nil)
+	if err := first.run(); err != nil {
+		return nil, err
+	}
+
+	second := newOctoAssembler(source, first.labels)
+	if err := second.run(); err != nil {
+		return nil, err
+	}
+	return second.memory[cpu.START_ADDRESS:second.end], nil
+}
+
+type octoToken struct {
+	text string
+	line int
+}
+
+// octoFlow is an open loop, if begin or else, and the jumps to patch when
+// it ends
+type octoFlow struct {
+	kind  string
+	start int
+	jumps []int
+}
+
+type octoAssembler struct {
+	tokens []octoToken
+	pos    int
+
+	memory    [4096]byte
+	here, end int
+
+	known   map[string]int // Labels from the first pass, nil during it
+	labels  map[string]int
+	consts  map[string]int
+	aliases map[string]int
+	flow    []*octoFlow
+}
+
+func newOctoAssembler(source string, known map[string]int) *octoAssembler {
+	a := &octoAssembler{
+		known:   known,
+		labels:  map[string]int{},
+		consts:  map[string]int{},
+		aliases: map[string]int{},
+	}
+
+	for n, line := range strings.Split(source, "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		for _, field := range strings.Fields(line) {
+			a.tokens = append(a.tokens, octoToken{field, n + 1})
+		}
+	}
+	return a
+}
+
+func (a *octoAssembler) errorf(format string, args ...interface{}) error {
+	line := 0
+	if a.pos > 0 {
+		line = a.tokens[a.pos-1].line
+	}
+	return fmt.Errorf("octo: line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (a *octoAssembler) unsupported(token string) error {
+	return fmt.Errorf("%w (line %d: %q)", ErrOctoSource, a.tokens[a.pos-1].line, token)
+}
+
+func (a *octoAssembler) next() (string, error) {
+	if a.pos == len(a.tokens) {
+		return "", a.errorf("unexpected end of program")
+	}
+	a.pos++
+	return a.tokens[a.pos-1].text, nil
+}
+
+func (a *octoAssembler) expect(want string) error {
+	token, err := a.next()
+	if err == nil && token != want {
+		err = a.errorf("expected %q, found %q", want, token)
+	}
+	return err
+}
+
+func (a *octoAssembler) run() error {
+	// The jump to main, dropped if main comes first
+	a.here = cpu.START_ADDRESS + 2
+	a.end = a.here
+
+	for a.pos < len(a.tokens) {
+		if err := a.statement(); err != nil {
+			return err
+		}
+	}
+
+	if len(a.flow) > 0 {
+		return a.errorf("%s without an end", a.flow[len(a.flow)-1].kind)
+	}
+
+	main, ok := a.labels["main"]
+	if !ok {
+		return fmt.Errorf("octo: the program has no main label")
+	}
+	if main != cpu.START_ADDRESS {
+		a.patch(cpu.START_ADDRESS, 0x1000|uint16(main))
+	}
+	return nil
+}
+
+func (a *octoAssembler) emit(data ...byte) error {
+	if a.here+len(data) > len(a.memory) {
+		return a.errorf("the program doesn't fit in memory")
+	}
+	copy(a.memory[a.here:], data)
+	a.here += len(data)
+	a.end = max(a.end, a.here)
+	return nil
+}
+
+func (a *octoAssembler) emitOpcode(opcode uint16) error {
+	return a.emit(byte(opcode>>8), byte(opcode))
+}
+
+func (a *octoAssembler) patch(address int, opcode uint16) {
+	a.memory[address] = byte(opcode >> 8)
+	a.memory[address+1] = byte(opcode)
+}
+
+// value reads a number, constant or label that fits in bits, negative
+// numbers too for bytes
+func (a *octoAssembler) value(bits int) (int, error) {
+	token, err := a.next()
+	if err != nil {
+		return 0, err
+	}
+
+	v, ok := a.consts[token]
+	if !ok {
+		v, ok = a.labels[token]
+	}
+	if !ok && a.known != nil {
+		v, ok = a.known[token]
+	}
+	if !ok {
+		n, number := octoNumber(token)
+		switch {
+		case number:
+			v = n
+		case strings.HasPrefix(token, "{"):
+			return 0, a.unsupported(token)
+		case a.known != nil:
+			return 0, a.errorf("undefined name %q", token)
+		}
+		// Labels defined further on are 0 during the first pass
+	}
+
+	lowest := 0
+	if bits == 8 {
+		lowest = -128
+	}
+	if v < lowest || v >= 1<<bits {
+		return 0, a.errorf("%s doesn't fit in %d bits", token, bits)
+	}
+	return v & (1<<bits - 1), nil
+}
+
+// octoNumber parses a decimal, 0x hex or 0b binary number
+func octoNumber(token string) (int, bool) {
+	digits, base := strings.TrimPrefix(token, "-"), 10
+	switch {
+	case strings.HasPrefix(digits, "0x"):
+		digits, base = digits[2:], 16
+	case strings.HasPrefix(digits, "0b"):
+		digits, base = digits[2:], 2
+	}
+
+	n, err := strconv.ParseInt(digits, base, 32)
+	if strings.HasPrefix(token, "-") {
+		n = -n
+	}
+	return int(n), err == nil
+}
+
+// register reads v0 to vF or an alias
+func (a *octoAssembler) register() (uint16, error) {
+	token, err := a.next()
+	if err != nil {
+		return 0, err
+	}
+	if r, ok := octoRegister(token); ok {
+		return r, nil
+	}
+	if r, ok := a.aliases[token]; ok {
+		return uint16(r), nil
+	}
+	return 0, a.errorf("expected a register, found %q", token)
+}
+
+func octoRegister(token string) (uint16, bool) {
+	if len(token) != 2 || (token[0] != 'v' && token[0] != 'V') {
+		return 0, false
+	}
+	r, err := strconv.ParseUint(token[1:], 16, 4)
+	return uint16(r), err == nil
+}
+
+func (a *octoAssembler) isRegister(token string) bool {
+	_, ok := octoRegister(token)
+	_, alias := a.aliases[token]
+	return ok || alias
+}
+
+// Instructions without operands
+var octoOpcodes = map[string]uint16{
+	"clear":        0x00E0,
+	"return":       0x00EE,
+	";":            0x00EE,
+	"scroll-right": 0x00FB,
+	"scroll-left":  0x00FC,
+	"exit":         0x00FD,
+	"lores":        0x00FE,
+	"hires":        0x00FF,
+	"audio":        0xF002,
+}
+
+// Instructions taking a register as x
+var octoRegisterOpcodes = map[string]uint16{
+	"bcd":       0xF033,
+	"saveflags": 0xF075,
+	"loadflags": 0xF085,
+}
+
+// Instructions of vx op vy
+var octoArithmetic = map[string]uint16{
+	":=":  0x8000,
+	"|=":  0x8001,
+	"&=":  0x8002,
+	"^=":  0x8003,
+	"+=":  0x8004,
+	"-=":  0x8005,
+	">>=": 0x8006,
+	"=-":  0x8007,
+	"<<=": 0x800E,
+}
+
+func (a *octoAssembler) statement() error {
+	token, err := a.next()
+	if err != nil {
+		return err
+	}
+
+	if opcode, ok := octoOpcodes[token]; ok {
+		return a.emitOpcode(opcode)
+	}
+	if opcode, ok := octoRegisterOpcodes[token]; ok {
+		x, err := a.register()
+		if err != nil {
+			return err
+		}
+		return a.emitOpcode(opcode | x<<8)
+	}
+	if a.isRegister(token) {
+		a.pos--
+		return a.assignRegister()
+	}
+
+	switch token {
+	case ":":
+		return a.label()
+	case ":const":
+		name, err := a.next()
+		if err != nil {
+			return err
+		}
+		v, err := a.value(16)
+		a.consts[name] = v
+		return err
+	case ":alias":
+		name, err := a.next()
+		if err != nil {
+			return err
+		}
+		r, err := a.register()
+		a.aliases[name] = int(r)
+		return err
+	case ":org":
+		v, err := a.value(12)
+		if err != nil {
+			return err
+		}
+		if v < cpu.START_ADDRESS {
+			return a.errorf(":org %#x is before the program", v)
+		}
+		a.here = v
+		return nil
+	case ":byte":
+		v, err := a.value(8)
+		if err != nil {
+			return err
+		}
+		return a.emit(byte(v))
+	case ":pointer":
+		v, err := a.value(16)
+		if err != nil {
+			return err
+		}
+		return a.emitOpcode(uint16(v))
+	case ":call":
+		return a.addressed(0x2000)
+	case ":unpack":
+		return a.unpack()
+	case ":monitor":
+		// Debugger settings, the address and size
+		a.pos = min(a.pos+2, len(a.tokens))
+		return nil
+	case ":breakpoint":
+		a.pos = min(a.pos+1, len(a.tokens))
+		return nil
+	case "jump":
+		return a.addressed(0x1000)
+	case "jump0":
+		return a.addressed(0xB000)
+	case "native":
+		return a.addressed(0x0000)
+	case "i":
+		return a.assignIndex()
+	case "delay", "buzzer", "pitch":
+		if err := a.expect(":="); err != nil {
+			return err
+		}
+		x, err := a.register()
+		if err != nil {
+			return err
+		}
+		opcode := map[string]uint16{"delay": 0xF015, "buzzer": 0xF018, "pitch": 0xF03A}[token]
+		return a.emitOpcode(opcode | x<<8)
+	case "save", "load":
+		return a.saveLoad(token)
+	case "sprite":
+		x, err := a.register()
+		if err != nil {
+			return err
+		}
+		y, err := a.register()
+		if err != nil {
+			return err
+		}
+		n, err := a.value(4)
+		if err != nil {
+			return err
+		}
+		return a.emitOpcode(0xD000 | x<<8 | y<<4 | uint16(n))
+	case "scroll-down", "scroll-up":
+		n, err := a.value(4)
+		if err != nil {
+			return err
+		}
+		opcode := map[string]uint16{"scroll-down": 0x00C0, "scroll-up": 0x00D0}[token]
+		return a.emitOpcode(opcode | uint16(n))
+	case "plane":
+		n, err := a.value(4)
+		if err != nil {
+			return err
+		}
+		return a.emitOpcode(0xF001 | uint16(n)<<8)
+	case "if", "while":
+		return a.condition(token)
+	case "loop":
+		a.flow = append(a.flow, &octoFlow{kind: "loop", start: a.here})
+		return nil
+	case "again", "else", "end":
+		return a.closeFlow(token)
+	}
+
+	if _, ok := octoNumber(token); ok {
+		a.pos--
+		v, err := a.value(8)
+		if err != nil {
+			return err
+		}
+		return a.emit(byte(v))
+	}
+
+	if strings.HasPrefix(token, ":") || strings.HasPrefix(token, "{") {
+		return a.unsupported(token)
+	}
+
+	// A call to a label, or a constant as a byte
+	if v, ok := a.consts[token]; ok {
+		return a.emit(byte(v))
+	}
+	a.pos--
+	return a.addressed(0x2000)
+}
+
+func (a *octoAssembler) label() error {
+	name, err := a.next()
+	if err != nil {
+		return err
+	}
+	if _, ok := a.labels[name]; ok {
+		return a.errorf("label %q defined twice", name)
+	}
+
+	if name == "main" && a.here == cpu.START_ADDRESS+2 && a.end == a.here {
+		a.here, a.end = cpu.START_ADDRESS, cpu.START_ADDRESS
+	}
+	a.labels[name] = a.here
+	return nil
+}
+
+// addressed emits opcode with a 12 bit address
+func (a *octoAssembler) addressed(opcode uint16) error {
+	v, err := a.value(12)
+	if err != nil {
+		return err
+	}
+	return a.emitOpcode(opcode | uint16(v))
+}
+
+// unpack loads the address of a label to v0 and v1, with a nibble in the
+// high bits of v0 or the whole address with long
+func (a *octoAssembler) unpack() error {
+	high := 0
+	if a.pos < len(a.tokens) && a.tokens[a.pos].text == "long" {
+		a.pos++
+	} else {
+		n, err := a.value(4)
+		if err != nil {
+			return err
+		}
+		high = n << 4
+	}
+
+	v, err := a.value(16)
+	if err != nil {
+		return err
+	}
+	if err := a.emitOpcode(0x6000 | uint16(high|v>>8&0xFF)); err != nil {
+		return err
+	}
+	return a.emitOpcode(0x6100 | uint16(v&0xFF))
+}
+
+func (a *octoAssembler) assignRegister() error {
+	x, err := a.register()
+	if err != nil {
+		return err
+	}
+	op, err := a.next()
+	if err != nil {
+		return err
+	}
+
+	arithmetic, ok := octoArithmetic[op]
+	if !ok {
+		return a.errorf("unknown operator %q", op)
+	}
+
+	if a.pos < len(a.tokens) && a.isRegister(a.tokens[a.pos].text) {
+		y, err := a.register()
+		if err != nil {
+			return err
+		}
+		return a.emitOpcode(arithmetic | x<<8 | y<<4)
+	}
+
+	if op == ":=" && a.pos < len(a.tokens) {
+		switch a.tokens[a.pos].text {
+		case "random":
+			a.pos++
+			n, err := a.value(8)
+			if err != nil {
+				return err
+			}
+			return a.emitOpcode(0xC000 | x<<8 | uint16(n))
+		case "key":
+			a.pos++
+			return a.emitOpcode(0xF00A | x<<8)
+		case "delay":
+			a.pos++
+			return a.emitOpcode(0xF007 | x<<8)
+		}
+	}
+
+	n, err := a.value(8)
+	if err != nil {
+		return err
+	}
+	switch op {
+	case ":=":
+		return a.emitOpcode(0x6000 | x<<8 | uint16(n))
+	case "+=":
+		return a.emitOpcode(0x7000 | x<<8 | uint16(n))
+	case "-=":
+		return a.emitOpcode(0x7000 | x<<8 | uint16(-n&0xFF))
+	}
+	return a.errorf("%s takes a register", op)
+}
+
+func (a *octoAssembler) assignIndex() error {
+	op, err := a.next()
+	if err != nil {
+		return err
+	}
+
+	if op == "+=" {
+		x, err := a.register()
+		if err != nil {
+			return err
+		}
+		return a.emitOpcode(0xF01E | x<<8)
+	}
+	if op != ":=" {
+		return a.errorf("unknown operator %q", op)
+	}
+
+	switch a.tokens[min(a.pos, len(a.tokens)-1)].text {
+	case "long":
+		a.pos++
+		v, err := a.value(16)
+		if err != nil {
+			return err
+		}
+		if err := a.emitOpcode(0xF000); err != nil {
+			return err
+		}
+		return a.emitOpcode(uint16(v))
+	case "hex", "bighex":
+		opcode := map[string]uint16{"hex": 0xF029, "bighex": 0xF030}[a.tokens[a.pos].text]
+		a.pos++
+		x, err := a.register()
+		if err != nil {
+			return err
+		}
+		return a.emitOpcode(opcode | x<<8)
+	}
+	return a.addressed(0xA000)
+}
+
+// saveLoad emits save vx, load vx or their vx - vy ranges
+func (a *octoAssembler) saveLoad(op string) error {
+	x, err := a.register()
+	if err != nil {
+		return err
+	}
+
+	if a.pos < len(a.tokens) && a.tokens[a.pos].text == "-" {
+		a.pos++
+		y, err := a.register()
+		if err != nil {
+			return err
+		}
+		opcode := map[string]uint16{"save": 0x5002, "load": 0x5003}[op]
+		return a.emitOpcode(opcode | x<<8 | y<<4)
+	}
+
+	opcode := map[string]uint16{"save": 0xF055, "load": 0xF065}[op]
+	return a.emitOpcode(opcode | x<<8)
+}
+
+// skipUnless reads a condition and returns the instruction skipping the
+// next one unless it holds
+func (a *octoAssembler) skipUnless() (uint16, error) {
+	x, err := a.register()
+	if err != nil {
+		return 0, err
+	}
+	op, err := a.next()
+	if err != nil {
+		return 0, err
+	}
+
+	switch op {
+	case "key":
+		return 0xE0A1 | x<<8, nil
+	case "-key":
+		return 0xE09E | x<<8, nil
+	case "==", "!=":
+	case "<", ">", "<=", ">=":
+		return 0, a.unsupported(op)
+	default:
+		return 0, a.errorf("unknown comparison %q", op)
+	}
+
+	if a.pos < len(a.tokens) && a.isRegister(a.tokens[a.pos].text) {
+		y, err := a.register()
+		if err != nil {
+			return 0, err
+		}
+		return map[string]uint16{"==": 0x9000, "!=": 0x5000}[op] | x<<8 | y<<4, nil
+	}
+
+	n, err := a.value(8)
+	if err != nil {
+		return 0, err
+	}
+	return map[string]uint16{"==": 0x4000, "!=": 0x3000}[op] | x<<8 | uint16(n), nil
+}
+
+// inverse returns the skip with the opposite condition
+func inverse(skip uint16) uint16 {
+	switch skip & 0xF000 {
+	case 0xE000:
+		return skip ^ 0x003F // Ex9E and ExA1
+	case 0x3000:
+		return skip + 0x1000
+	case 0x4000:
+		return skip - 0x1000
+	}
+	return skip ^ 0xC000 // 5xy0 and 9xy0
+}
+
+// condition emits if ... then, if ... begin and while ... in a loop
+func (a *octoAssembler) condition(keyword string) error {
+	skip, err := a.skipUnless()
+	if err != nil {
+		return err
+	}
+
+	if keyword == "if" {
+		then, err := a.next()
+		if err != nil {
+			return err
+		}
+		switch then {
+		case "then":
+			return a.emitOpcode(skip)
+		case "begin":
+		default:
+			return a.errorf("expected then or begin, found %q", then)
+		}
+	}
+
+	// Jump away when the condition doesn't hold, to the else or end of an
+	// if and past a loop
+	if err := a.emitOpcode(inverse(skip)); err != nil {
+		return err
+	}
+
+	if keyword == "if" {
+		a.flow = append(a.flow, &octoFlow{kind: "if", jumps: []int{a.here}})
+	} else {
+		loop := a.innermost("loop")
+		if loop == nil {
+			return a.errorf("while outside of a loop")
+		}
+		loop.jumps = append(loop.jumps, a.here)
+	}
+	return a.emitOpcode(0x1000)
+}
+
+// innermost returns the innermost open flow of kind
+func (a *octoAssembler) innermost(kind string) *octoFlow {
+	for i := len(a.flow) - 1; i >= 0; i-- {
+		if a.flow[i].kind == kind {
+			return a.flow[i]
+		}
+	}
+	return nil
+}
+
+func (a *octoAssembler) closeFlow(keyword string) error {
+	want := map[string]string{"again": "loop", "else": "if", "end": "if"}[keyword]
+	if len(a.flow) == 0 {
+		return a.errorf("%s without %s", keyword, want)
+	}
+	flow := a.flow[len(a.flow)-1]
+	if flow.kind != want && !(keyword == "end" && flow.kind == "else") {
+		return a.errorf("%s closing %s", keyword, flow.kind)
+	}
+	a.flow = a.flow[:len(a.flow)-1]
+
+	switch keyword {
+	case "again":
+		if err := a.emitOpcode(0x1000 | uint16(flow.start)); err != nil {
+			return err
+		}
+	case "else":
+		if err := a.emitOpcode(0x1000); err != nil {
+			return err
+		}
+		a.flow = append(a.flow, &octoFlow{kind: "else", jumps: []int{a.here - 2}})
+	}
+
+	for _, jump := range flow.jumps {
+		a.patch(jump, 0x1000|uint16(a.here))
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestAssembleOcto(t *testing.T) {
+	source := `
+# Bounce a ball
+:const SPEED 2
+:alias x v0
+:alias y v1
+
+: ball
+	0x80
+
+: draw
+	i := ball
+	sprite x y 1
+;
+
+: main
+	clear
+	x := 10
+	y := 5
+	loop
+		draw
+		x += SPEED
+		if x == 60 begin
+			x := 0
+		else
+			y += 1
+		end
+		v2 := key
+		while v2 != 0xF
+	again
+	loop again
+`
+	want := []byte{
+		0x12, 0x09, // jump main
+		0x80,
+		0xA2, 0x02, 0xD0, 0x11, 0x00, 0xEE,
+		0x00, 0xE0, 0x60, 0x0A, 0x61, 0x05,
+		0x22, 0x03, 0x70, 0x02,
+		0x30, 0x3C, 0x12, 0x1B, 0x60, 0x00, 0x12, 0x1D, 0x71, 0x01,
+		0xF2, 0x0A, 0x42, 0x0F, 0x12, 0x25, 0x12, 0x0F,
+		0x12, 0x25,
+	}
+
+	rom, err := AssembleOcto(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rom, want) {
+		t.Errorf("Expected\n%x, got\n%x", want, rom)
+	}
+}
+
+func TestAssembleOctoInstructions(t *testing.T) {
+	tests := map[string][]byte{
+		"v3 -= 1":               {0x73, 0xFF},
+		"i := hex v3":           {0xF3, 0x29},
+		"i := long 0x1234":      {0xF0, 0x00, 0x12, 0x34},
+		":unpack 0xA main":      {0x60, 0xA2, 0x61, 0x00},
+		"vf := random 0x7F":     {0xCF, 0x7F},
+		"if v1 -key then exit":  {0xE1, 0x9E, 0x00, 0xFD},
+		"save v2 - v4":          {0x52, 0x42},
+		"load v5":               {0xF5, 0x65},
+		"v1 =- v2":              {0x81, 0x27},
+		"scroll-down 4 plane 3": {0x00, 0xC4, 0xF3, 0x01},
+	}
+
+	for source, want := range tests {
+		rom, err := AssembleOcto(": main " + source)
+		if err != nil || !bytes.Equal(rom, want) {
+			t.Errorf("Expected %q to be %x, got %x %v", source, want, rom, err)
+		}
+	}
+}
+
+func TestAssembleOctoErrors(t *testing.T) {
+	for _, source := range []string{":calc x { 1 + 2 }\n: main", ": main if v0 > 3 then clear"} {
+		if _, err := AssembleOcto(source); !errors.Is(err, ErrOctoSource) {
+			t.Errorf("Expected ErrOctoSource for %q, got %v", source, err)
+		}
+	}
+
+	for _, source := range []string{": main jump nowhere", "clear", ": main loop", ": main v0 := 256"} {
+		if _, err := AssembleOcto(source); err == nil || errors.Is(err, ErrOctoSource) {
+			t.Errorf("Expected an error for %q, got %v", source, err)
+		}
+	}
+}
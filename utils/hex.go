@@ -0,0 +1,257 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"github.com/brunocroh/chip8/cpu"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/*
+ROMs can also be written as text, either a plain hex dump:
+
+	# Clear the screen and loop
+	00 E0 12 02
+	0x6A02 ; words work too
+
+or Intel HEX records. Text is only read from files named .hex, .ihx or
+.txt, other files are binary ROMs.
+
+Plain dumps start at 0x200 and may set the address of the bytes that
+follow with "0300:", always a memory address. Intel HEX files give their
+own addresses, and one starting at address 0 is moved as a whole to 0x200,
+as most tools write ROMs that way.
+*/
+
+// File extensions of ROMs written as text
+var TextExtensions = []string{".hex", ".ihx", ".txt"}
+
+// memoryImage collects bytes written at memory addresses
+type memoryImage struct {
+	memory   [4096]byte
+	min, max int
+	written  bool
+}
+
+func (m *memoryImage) write(address int, data ...byte) error {
+	if address < 0 || address+len(data) > len(m.memory) {
+		return fmt.Errorf("data at %#x doesn't fit in memory", address)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	copy(m.memory[address:], data)
+	if !m.written || address < m.min {
+		m.min = address
+	}
+	if !m.written || address+len(data) > m.max {
+		m.max = address + len(data)
+	}
+	m.written = true
+	return nil
+}
+
+// rom returns the bytes from the lowest to the highest written address, gaps
+// filled with zeros, and where they go. With relocate, bytes starting at
+// address 0 go to cpu.START_ADDRESS.
+func (m *memoryImage) rom(relocate bool) ([]byte, uint16, error) {
+	if !m.written {
+		return nil, 0, fmt.Errorf("no data")
+	}
+
+	data := append([]byte(nil), m.memory[m.min:m.max]...)
+	address := m.min
+	if relocate && address == 0 {
+		address = cpu.START_ADDRESS
+	}
+
+	if address+len(data) > len(m.memory) {
+		return nil, 0, fmt.Errorf("%d bytes at %#x don't fit in memory", len(data), address)
+	}
+	return data, uint16(address), nil
+}
+
+// isIntelHex reports whether data starts with an Intel HEX record
+func isIntelHex(data []byte) bool {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	if len(data) < 11 || data[0] != ':' {
+		return false
+	}
+
+	for _, c := range data[1:11] {
+		if !isHexDigit(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// isText reports whether the file name holds a ROM written as text and data
+// is printable ASCII, checked before comments are stripped as #, ; and /
+// are common opcode bytes
+func isText(data []byte, name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	text := false
+	for _, e := range TextExtensions {
+		text = text || ext == e
+	}
+	if !text {
+		return false
+	}
+
+	for _, c := range data {
+		if (c < 0x20 || c > 0x7E) && c != '\t' && c != '\r' && c != '\n' {
+			return false
+		}
+	}
+	return true
+}
+
+// isHexText reports whether text is a plain hex dump, only hex digits, 0x
+// prefixes, addresses and separators outside of comments
+func isHexText(data []byte) bool {
+	digits := false
+	for _, line := range strings.Split(string(data), "\n") {
+		for _, c := range []byte(stripComment(line)) {
+			switch {
+			case isHexDigit(c):
+				digits = true
+			case c == 'x' || c == 'X' || c == ':' || c == ',' || unicode.IsSpace(rune(c)):
+			default:
+				return false
+			}
+		}
+	}
+	return digits
+}
+
+func stripComment(line string) string {
+	for _, marker := range []string{"#", ";", "//"} {
+		if i := strings.Index(line, marker); i >= 0 {
+			line = line[:i]
+		}
+	}
+	return line
+}
+
+// parseHexText reads a plain hex dump. Tokens are bytes or longer runs of
+// hex digits, with an optional 0x, and "addr:" sets the address.
+func parseHexText(data []byte) ([]byte, uint16, error) {
+	var image memoryImage
+	address := cpu.START_ADDRESS
+
+	for n, line := range strings.Split(string(data), "\n") {
+		fields := strings.FieldsFunc(stripComment(line), func(r rune) bool {
+			return unicode.IsSpace(r) || r == ','
+		})
+
+		for _, field := range fields {
+			if strings.HasSuffix(field, ":") {
+				v, err := strconv.ParseUint(strings.TrimPrefix(field[:len(field)-1], "0x"), 16, 12)
+				if err != nil {
+					return nil, 0, fmt.Errorf("hex: line %d: invalid address %q", n+1, field)
+				}
+				address = int(v)
+				continue
+			}
+
+			digits := strings.TrimPrefix(strings.TrimPrefix(field, "0x"), "0X")
+			b, err := hex.DecodeString(digits)
+			if err != nil || len(digits) == 0 {
+				return nil, 0, fmt.Errorf("hex: line %d: invalid bytes %q", n+1, field)
+			}
+
+			if err := image.write(address, b...); err != nil {
+				return nil, 0, fmt.Errorf("hex: line %d: %w", n+1, err)
+			}
+			address += len(b)
+		}
+	}
+
+	rom, at, err := image.rom(false)
+	if err != nil {
+		return nil, 0, fmt.Errorf("hex: %w", err)
+	}
+	return rom, at, nil
+}
+
+// Intel HEX record types
+const (
+	IHEX_DATA             = 0x00
+	IHEX_END_OF_FILE      = 0x01
+	IHEX_EXTENDED_SEGMENT = 0x02
+	IHEX_START_SEGMENT    = 0x03
+	IHEX_EXTENDED_LINEAR  = 0x04
+	IHEX_START_LINEAR     = 0x05
+)
+
+// parseIntelHex reads Intel HEX records, checking their checksums. Start
+// address records are ignored, CHIP-8 programs always start at 0x200.
+func parseIntelHex(data []byte) ([]byte, uint16, error) {
+	var image memoryImage
+	base := 0
+
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if line[0] != ':' {
+			return nil, 0, fmt.Errorf("intel hex: line %d: expected a record starting with ':'", n+1)
+		}
+
+		record, err := hex.DecodeString(line[1:])
+		if err != nil || len(record) < 5 || len(record) != int(record[0])+5 {
+			return nil, 0, fmt.Errorf("intel hex: line %d: malformed record", n+1)
+		}
+
+		var sum byte
+		for _, b := range record {
+			sum += b
+		}
+		if sum != 0 {
+			return nil, 0, fmt.Errorf("intel hex: line %d: checksum mismatch", n+1)
+		}
+
+		offset := int(record[1])<<8 | int(record[2])
+		payload := record[4 : len(record)-1]
+
+		switch record[3] {
+		case IHEX_DATA:
+			if err := image.write(base+offset, payload...); err != nil {
+				return nil, 0, fmt.Errorf("intel hex: line %d: %w", n+1, err)
+			}
+		case IHEX_END_OF_FILE:
+			rom, at, err := image.rom(true)
+			if err != nil {
+				return nil, 0, fmt.Errorf("intel hex: %w", err)
+			}
+			return rom, at, nil
+		case IHEX_EXTENDED_SEGMENT, IHEX_EXTENDED_LINEAR:
+			if len(payload) != 2 {
+				return nil, 0, fmt.Errorf("intel hex: line %d: malformed address record", n+1)
+			}
+			base = int(payload[0])<<8 | int(payload[1])
+			if record[3] == IHEX_EXTENDED_SEGMENT {
+				base <<= 4
+			} else {
+				base <<= 16
+			}
+		case IHEX_START_SEGMENT, IHEX_START_LINEAR:
+		default:
+			return nil, 0, fmt.Errorf("intel hex: line %d: unknown record type %#02x", n+1, record[3])
+		}
+	}
+
+	return nil, 0, fmt.Errorf("intel hex: missing end of file record")
+}
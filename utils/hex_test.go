@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"bytes"
+	"github.com/brunocroh/chip8/cpu"
+	"testing"
+)
+
+func TestReadRomHexText(t *testing.T) {
+	text := "# Clear and loop\n00 E0 12 02 ; jump\n0x6A02, // words\n"
+
+	rom, err := ReadRom([]byte(text), "loop.txt", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rom.Address != cpu.START_ADDRESS || !bytes.Equal(rom.Data, []byte{0x00, 0xE0, 0x12, 0x02, 0x6A, 0x02}) {
+		t.Errorf("Unexpected ROM %x at %#x", rom.Data, rom.Address)
+	}
+}
+
+func TestReadRomHexTextAddress(t *testing.T) {
+	rom, err := ReadRom([]byte("0300: 12 00\n0302: FF"), "data.hex", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rom.Address != 0x300 || !bytes.Equal(rom.Data, []byte{0x12, 0x00, 0xFF}) {
+		t.Errorf("Unexpected ROM %x at %#x", rom.Data, rom.Address)
+	}
+}
+
+func TestReadRomHexTextAbsolute(t *testing.T) {
+	rom, err := ReadRom([]byte("00 E0\n0300: FF"), "data.txt", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rom.Address != 0x200 || len(rom.Data) != 0x101 || rom.Data[0x100] != 0xFF {
+		t.Errorf("Expected 0300: to be a memory address, got %d bytes at %#x", len(rom.Data), rom.Address)
+	}
+}
+
+func TestReadRomBinary(t *testing.T) {
+	// LD V1, 0x23 holds a # and JP 0x202 isn't text
+	data := []byte{0x61, 0x23, 0x12, 0x02}
+
+	for _, name := range []string{"loop.ch8", "loop.txt"} {
+		rom, err := ReadRom(data, name, "")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if rom.Address != cpu.START_ADDRESS || !bytes.Equal(rom.Data, data) {
+			t.Errorf("%s: expected the data as is, got %x at %#x", name, rom.Data, rom.Address)
+		}
+	}
+
+	rom, err := ReadRom([]byte("00 E0 12 02"), "text.ch8", "")
+	if err != nil || string(rom.Data) != "00 E0 12 02" {
+		t.Errorf("Expected .ch8 files to be binary, got %v %v", rom, err)
+	}
+}
+
+func TestReadRomIntelHex(t *testing.T) {
+	text := ":0402000000E0120206\n:01020600FFF8\n:00000001FF\n"
+
+	rom, err := ReadRom([]byte(text), "loop.hex", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rom.Address != 0x200 || !bytes.Equal(rom.Data, []byte{0x00, 0xE0, 0x12, 0x02, 0x00, 0x00, 0xFF}) {
+		t.Errorf("Unexpected ROM %x at %#x", rom.Data, rom.Address)
+	}
+}
+
+func TestReadRomIntelHexRelative(t *testing.T) {
+	text := ":0200000000E01E\n:01001000FFF0\n:00000001FF\n"
+
+	rom, err := ReadRom([]byte(text), "loop.ihx", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rom.Address != 0x200 || len(rom.Data) != 0x11 || rom.Data[1] != 0xE0 || rom.Data[0x10] != 0xFF {
+		t.Errorf("Expected the records moved as a whole to 0x200, got %x at %#x", rom.Data, rom.Address)
+	}
+}
+
+func TestReadRomIntelHexChecksum(t *testing.T) {
+	if _, err := ReadRom([]byte(":0402000000E01202FF\n:00000001FF\n"), "bad.hex", ""); err == nil {
+		t.Error("Expected a checksum error")
+	}
+
+	if _, err := ReadRom([]byte(":0402000000E0120206\n"), "short.hex", ""); err == nil {
+		t.Error("Expected a missing end of file error")
+	}
+}
@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/brunocroh/chip8/romdb"
+	"image/gif"
+	"strings"
+)
+
+/*
+Octo cartridges are GIF images with the program hidden in the pixels: the
+two low bits of every palette index, in order across all frames and most
+significant first, make up a 4 byte big endian length followed by that
+many bytes of UTF-8 JSON:
+
+	{"options": {"tickrate": 20, "shiftQuirks": false, ...}, "program": ": main 0x00 0xE0 ..."}
+
+The program is Octo assembly source, assembled with AssembleOcto.
+*/
+type OctoCartridge struct {
+	Options OctoOptions `json:"options"`
+	Program string      `json:"program"`
+}
+
+type OctoOptions struct {
+	Tickrate        int    `json:"tickrate"`
+	BackgroundColor string `json:"backgroundColor"`
+	FillColor       string `json:"fillColor"`
+	FillColor2      string `json:"fillColor2"`
+	BlendColor      string `json:"blendColor"`
+	ShiftQuirks     bool   `json:"shiftQuirks"`
+	LoadStoreQuirks bool   `json:"loadStoreQuirks"`
+	JumpQuirks      bool   `json:"jumpQuirks"`
+	LogicQuirks     bool   `json:"logicQuirks"`
+	ClipQuirks      bool   `json:"clipQuirks"`
+	VBlankQuirks    bool   `json:"vBlankQuirks"`
+}
+
+var ErrOctoSource = errors.New("octo cartridge: the program uses Octo features the assembler here lacks")
+
+func isGif(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("GIF87a")) || bytes.HasPrefix(data, []byte("GIF89a"))
+}
+
+// ReadOctoCartridge extracts the JSON payload of a cartridge GIF.
+func ReadOctoCartridge(data []byte) (*OctoCartridge, error) {
+	img, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("octo cartridge: %w", err)
+	}
+
+	var payload []byte
+	var b byte
+	bits := 0
+	for _, frame := range img.Image {
+		r := frame.Bounds()
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				b = b<<2 | frame.ColorIndexAt(x, y)&3
+				bits += 2
+				if bits == 8 {
+					payload = append(payload, b)
+					b, bits = 0, 0
+				}
+			}
+		}
+	}
+
+	if len(payload) < 4 {
+		return nil, errors.New("octo cartridge: image too small for a payload")
+	}
+
+	size := binary.BigEndian.Uint32(payload)
+	if uint64(size) > uint64(len(payload)-4) {
+		return nil, fmt.Errorf("octo cartridge: payload of %d bytes doesn't fit in the image, not a cartridge?", size)
+	}
+
+	cart := &OctoCartridge{}
+	if err := json.Unmarshal(payload[4:4+size], cart); err != nil {
+		return nil, fmt.Errorf("octo cartridge: %w", err)
+	}
+	return cart, nil
+}
+
+// Bytes assembles the program. Programs using what AssembleOcto doesn't
+// support return ErrOctoSource.
+func (c *OctoCartridge) Bytes() ([]byte, error) {
+	return AssembleOcto(c.Program)
+}
+
+// Info returns the options as a ROM database entry, so they configure the
+// emulator like a known ROM.
+func (c *OctoCartridge) Info(name string) *romdb.Info {
+	o := c.Options
+	rom := &romdb.Rom{
+		File:      name,
+		Platforms: []string{"xochip"},
+		QuirkyPlatforms: map[string]map[string]bool{
+			"xochip": {
+				"shift":                 o.ShiftQuirks,
+				"memoryLeaveIUnchanged": o.LoadStoreQuirks,
+				"jump":                  o.JumpQuirks,
+				"logic":                 o.LogicQuirks,
+				"wrap":                  !o.ClipQuirks,
+				"vblank":                o.VBlankQuirks,
+			},
+		},
+		Tickrate: o.Tickrate,
+	}
+
+	if o.BackgroundColor != "" && o.FillColor != "" {
+		rom.Colors = &romdb.Colors{Pixels: []string{o.BackgroundColor, o.FillColor}}
+		if o.FillColor2 != "" && o.BlendColor != "" {
+			rom.Colors.Pixels = append(rom.Colors.Pixels, o.FillColor2, o.BlendColor)
+		}
+	}
+
+	program := &romdb.Program{
+		Title: strings.TrimSuffix(name, ".gif"),
+		Roms:  map[string]*romdb.Rom{"": rom},
+	}
+	return &romdb.Info{Program: program, Rom: rom}
+}
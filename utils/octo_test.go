@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/brunocroh/chip8/cpu"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+// cartridge hides payload in a GIF the way Octo does
+func cartridge(t *testing.T, cart OctoCartridge) []byte {
+	payload, err := json.Marshal(cart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload = append(binary.BigEndian.AppendUint32(nil, uint32(len(payload))), payload...)
+
+	palette := color.Palette{}
+	for i := 0; i < 4; i++ {
+		palette = append(palette, color.Gray{uint8(i * 60)})
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, 64, 64), palette)
+	for i, b := range payload {
+		for j := 0; j < 4; j++ {
+			img.Pix[i*4+j] = b >> (6 - 2*j) & 3
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadRomOctoCartridge(t *testing.T) {
+	data := cartridge(t, OctoCartridge{
+		Options: OctoOptions{Tickrate: 20, BackgroundColor: "#996600", FillColor: "#FFCC00", ClipQuirks: true},
+		Program: ": main\n0x00 0xE0 # clear\n18 0b00000010\n",
+	})
+
+	rom, err := ReadRom(data, "game.gif", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(rom.Data, []byte{0x00, 0xE0, 0x12, 0x02}) {
+		t.Errorf("Unexpected program %x", rom.Data)
+	}
+
+	if rom.Info == nil || rom.Info.Tickrate != 20 {
+		t.Fatalf("Expected the cartridge options as Info, got %+v", rom.Info)
+	}
+
+	want := cpu.Quirks{ShiftVy: true, Clip: true}
+	if q := rom.Info.Quirks(); q != want {
+		t.Errorf("Quirks = %+v, want %+v", q, want)
+	}
+
+	if palette, ok := rom.Info.Palette(); !ok || palette.Colors[0].R != 0x99 {
+		t.Errorf("Unexpected palette %+v", palette)
+	}
+}
+
+func TestReadRomOctoSource(t *testing.T) {
+	data := cartridge(t, OctoCartridge{Program: ":macro twice X { X X }\n: main twice clear"})
+
+	if _, err := ReadRom(data, "game.gif", ""); !errors.Is(err, ErrOctoSource) {
+		t.Errorf("Expected ErrOctoSource, got %v", err)
+	}
+}
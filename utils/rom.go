@@ -1,10 +1,85 @@
 package utils
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"github.com/brunocroh/chip8/cpu"
+	"github.com/brunocroh/chip8/romdb"
 	"os"
+	"path/filepath"
 )
 
-func LoadRom(path string) ([]byte, error) {
+// The CHIP-8 memory, which ROMs have to fit in from their address
+const MEMORY_SIZE = 4096
+
+type Rom struct {
+	Name    string // File name, of the entry for ROMs from archives
+	Data    []byte
+	Address uint16      // Where Data is loaded in memory
+	SHA1    string      // Hex encoded hash of Data
+	Info    *romdb.Info // Entry in the ROM database, nil for unknown ROMs
+}
+
+// NewRom hashes data and looks it up in the ROM database.
+func NewRom(data []byte) *Rom {
+	sum := sha1.Sum(data)
+	rom := &Rom{Data: data, Address: cpu.START_ADDRESS, SHA1: hex.EncodeToString(sum[:])}
+
+	if info, ok := romdb.Lookup(rom.SHA1); ok {
+		rom.Info = info
+	}
+
+	return rom
+}
+
+// ReadRom reads the ROM in data, the contents of the file name. Zip and
+// gzip archives are unpacked, and entry picks the ROM in zip archives that
+// hold several, by name or by number from 1 (see ArchiveError). Octo
+// cartridge GIFs are read with their options as Info, and hex dumps and
+// Intel HEX files at the addresses they give.
+func ReadRom(data []byte, name, entry string) (*Rom, error) {
+	data, name, err := unpack(data, name, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	address := uint16(cpu.START_ADDRESS)
+	switch {
+	case isGif(data):
+		return readOctoCartridge(data, name)
+	case isText(data, name) && isIntelHex(data):
+		data, address, err = parseIntelHex(data)
+	case isText(data, name) && isHexText(data):
+		data, address, err = parseHexText(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSize(name, address, data); err != nil {
+		return nil, err
+	}
+
+	rom := NewRom(data)
+	rom.Address = address
+	rom.Name = name
+	return rom, nil
+}
+
+func checkSize(name string, address uint16, data []byte) error {
+	if int(address)+len(data) > MEMORY_SIZE {
+		return fmt.Errorf("%s: %d bytes at %#x don't fit in the %d bytes of memory", name, len(data), address, MEMORY_SIZE)
+	}
+	return nil
+}
+
+func LoadRom(path string) (*Rom, error) {
+	return LoadRomEntry(path, "")
+}
+
+// LoadRomEntry loads the ROM at path, picking entry in archives as ReadRom
+// does.
+func LoadRomEntry(path, entry string) (*Rom, error) {
 	data, err := os.ReadFile(path)
 
 	if err != nil {
@@ -12,5 +87,28 @@ func LoadRom(path string) ([]byte, error) {
 
 	}
 
-	return data, nil
+	return ReadRom(data, filepath.Base(path), entry)
+}
+
+// readOctoCartridge loads the program of an Octo cartridge, its options
+// taking precedence over the ROM database
+func readOctoCartridge(data []byte, name string) (*Rom, error) {
+	cart, err := ReadOctoCartridge(data)
+	if err != nil {
+		return nil, err
+	}
+
+	program, err := cart.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSize(name, cpu.START_ADDRESS, program); err != nil {
+		return nil, err
+	}
+
+	rom := NewRom(program)
+	rom.Name = name
+	rom.Info = cart.Info(name)
+	rom.Info.SHA1 = rom.SHA1
+	return rom, nil
 }
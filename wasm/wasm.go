@@ -3,26 +3,58 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
 	"github.com/brunocroh/chip8/cpu"
+	"github.com/brunocroh/chip8/keymap"
+	"github.com/brunocroh/chip8/render"
+	"github.com/brunocroh/chip8/utils"
+	"image/color"
 	"os"
 	"syscall/js"
 	"time"
-	"unsafe"
 )
 
 var (
 	keepRunning bool = true
 	romLoaded   bool = false
+	started     bool = false
 	chip8       *cpu.Chip8
+	renderCb    js.Value
+
+	keymapConfig   *keymap.Config
+	keys           keymap.Keymap
+	gamepadKeys    keymap.Keymap
+	gamepadMapping keymap.Keymap
+	romName        string
+	romHints       keymap.Hints // Keys of the loaded ROM from the ROM database
+
+	palette     render.Palette      = render.DefaultPalette
+	persistence *render.Persistence = render.NewPersistence(render.PERSISTENCE_OFF)
+	effects     *render.Effects     = &render.Effects{Scale: 1}
 )
 
 func main() {
 	js.Global().Set("loadRom", js.FuncOf(loadRomJS))
 	js.Global().Set("start", js.FuncOf(startJS))
 	js.Global().Set("onKeyEvent", js.FuncOf(onKeyEvent))
+	js.Global().Set("onKey", js.FuncOf(onKeyJS))
+	js.Global().Set("setKeymapConfig", js.FuncOf(setKeymapConfigJS))
+	js.Global().Set("setKeymapLayout", js.FuncOf(setKeymapLayoutJS))
+	js.Global().Set("onGamepadInput", js.FuncOf(onGamepadInputJS))
+	js.Global().Set("setGamepadMapping", js.FuncOf(setGamepadMappingJS))
+	js.Global().Set("setPalette", js.FuncOf(setPaletteJS))
+	js.Global().Set("getPalettes", js.FuncOf(getPalettesJS))
+	js.Global().Set("setPersistence", js.FuncOf(setPersistenceJS))
+	js.Global().Set("setFilters", js.FuncOf(setFiltersJS))
+	js.Global().Set("screenshot", js.FuncOf(screenshotJS))
+	js.Global().Set("setCyclesPerFrame", js.FuncOf(setCyclesPerFrameJS))
+	js.Global().Set("setTimingMode", js.FuncOf(setTimingModeJS))
+	js.Global().Set("setVBlankWait", js.FuncOf(setVBlankWaitJS))
 
 	chip8 = cpu.NewChip8()
 	chip8.Init()
+	resolveKeymaps()
 
 	select {}
 }
@@ -40,33 +72,305 @@ func onKeyEvent(this js.Value, args []js.Value) interface{} {
 	return nil
 }
 
-func loadRomJS(this js.Value, args []js.Value) interface{} {
+// onKeyJS receives a KeyboardEvent.key and maps it with the current keymap.
+// It returns true when the key is bound to the keypad.
+func onKeyJS(this js.Value, args []js.Value) interface{} {
+	if len(args) != 2 {
+		return false
+	}
+
+	key, ok := keys.Lookup(args[0].String())
+	if !ok {
+		return false
+	}
+
+	chip8.OnKeyEvent(key, uint8(args[1].Int()))
+
+	return true
+}
+
+// setKeymapConfigJS receives the contents of a keymap JSON file and returns
+// an error message, or null when it was applied.
+func setKeymapConfigJS(this js.Value, args []js.Value) interface{} {
 	if len(args) != 1 {
 		return nil
 	}
 
+	config, err := keymap.Parse([]byte(args[0].String()))
+	if err != nil {
+		return err.Error()
+	}
+
+	keymapConfig = config
+	resolveKeymaps()
+
+	return nil
+}
+
+// setKeymapLayoutJS receives a layout preset name and applies it on top of
+// the current keymap file. It returns an error message, or null when
+// applied.
+func setKeymapLayoutJS(this js.Value, args []js.Value) interface{} {
+	if len(args) != 1 {
+		return nil
+	}
+
+	config, err := keymapConfig.WithLayout(args[0].String())
+	if err != nil {
+		return err.Error()
+	}
+
+	keymapConfig = config
+	resolveKeymaps()
+
+	return nil
+}
+
+// onGamepadInputJS receives a controller input name (see keymap.Controller)
+// and maps it with the current gamepad mapping. It returns true when the
+// input is bound to the keypad.
+func onGamepadInputJS(this js.Value, args []js.Value) interface{} {
+	if len(args) != 2 {
+		return false
+	}
+
+	key, ok := gamepadKeys.Lookup(args[0].String())
+	if !ok {
+		return false
+	}
+
+	chip8.OnKeyEvent(key, uint8(args[1].Int()))
+
+	return true
+}
+
+// setGamepadMappingJS receives a JSON object of controller bindings applied
+// on top of the keymap file, and returns an error message or null.
+func setGamepadMappingJS(this js.Value, args []js.Value) interface{} {
+	if len(args) != 1 {
+		return nil
+	}
+
+	mapping, err := keymap.ParseBindings([]byte(args[0].String()))
+	if err != nil {
+		return err.Error()
+	}
+
+	gamepadMapping = mapping
+	resolveKeymaps()
+
+	return nil
+}
+
+func resolveKeymaps() {
+	config := keymapConfig
+	if romHints != nil {
+		config = config.WithHints(romHints)
+	}
+
+	keys, _ = config.Keymap(romName)
+	gamepadKeys, _ = config.ControllerKeymap(romName)
+
+	for name, key := range gamepadMapping {
+		gamepadKeys[name] = key
+	}
+}
+
+// setPaletteJS receives a palette name or custom colours (see
+// render.ParsePalette) and returns an error message, or null when applied.
+func setPaletteJS(this js.Value, args []js.Value) interface{} {
+	if len(args) != 1 {
+		return nil
+	}
+
+	p, err := render.ParsePalette(args[0].String())
+	if err != nil {
+		return err.Error()
+	}
+
+	palette = p
+	chip8.SetDrawFlag(true)
+
+	return nil
+}
+
+func getPalettesJS(this js.Value, args []js.Value) interface{} {
+	names := []interface{}{}
+	for _, p := range render.Palettes {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// setPersistenceJS receives an anti-flicker mode (see
+// render.ParsePersistence) and returns an error message, or null when applied.
+func setPersistenceJS(this js.Value, args []js.Value) interface{} {
+	if len(args) != 1 {
+		return nil
+	}
+
+	p, err := render.ParsePersistence(args[0].String())
+	if err != nil {
+		return err.Error()
+	}
+
+	persistence = p
+	chip8.SetDrawFlag(true)
+
+	return nil
+}
+
+// setFiltersJS receives a comma separated list of CRT filters (see
+// render.ParseEffects) and returns an error message, or null when applied.
+func setFiltersJS(this js.Value, args []js.Value) interface{} {
+	if len(args) != 1 {
+		return nil
+	}
+
+	e, err := render.ParseEffects(args[0].String(), render.DEFAULT_FILTER_SCALE)
+	if err != nil {
+		return err.Error()
+	}
+
+	effects = e
+	chip8.SetDrawFlag(true)
+
+	return nil
+}
+
+// screenshotJS returns the display as PNG bytes in a Uint8Array, scaled by
+// args[0] (8 by default).
+func screenshotJS(this js.Value, args []js.Value) interface{} {
+	scale := 8
+	if len(args) > 0 {
+		scale = args[0].Int()
+	}
+
+	var buf bytes.Buffer
+	if err := render.WritePNG(&buf, render.Screenshot(&chip8.Video, palette, scale)); err != nil {
+		return nil
+	}
+
+	png := js.Global().Get("Uint8Array").New(buf.Len())
+	js.CopyBytesToJS(png, buf.Bytes())
+
+	return png
+}
+
+func setCyclesPerFrameJS(this js.Value, args []js.Value) interface{} {
+	if len(args) != 1 {
+		return nil
+	}
+
+	chip8.SetCyclesPerFrame(args[0].Int())
+
+	return nil
+}
+
+func setTimingModeJS(this js.Value, args []js.Value) interface{} {
+	if len(args) != 1 {
+		return nil
+	}
+
+	timing, ok := cpu.ParseTimingMode(args[0].String())
+	if !ok {
+		return nil
+	}
+
+	chip8.SetTimingMode(timing)
+
+	return nil
+}
+
+func setVBlankWaitJS(this js.Value, args []js.Value) interface{} {
+	if len(args) != 1 {
+		return nil
+	}
+
+	quirks := chip8.Quirks()
+	quirks.VBlankWait = args[0].Bool()
+	chip8.SetQuirks(quirks)
+
+	return nil
+}
+
+// loadRomJS receives the ROM bytes and file name, which may be a zip or gzip
+// archive holding a single ROM. ROMs found in the ROM database get their
+// quirks and keys, and the settings the page controls are returned as
+// {title, tickrate, vblank, colors}. It returns null for unknown ROMs and
+// {error} when the ROM can't be read.
+func loadRomJS(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return nil
+	}
+
+	name := ""
+	if len(args) > 1 {
+		name = args[1].String()
+	}
+
 	uints8Array := args[0]
 	length := uints8Array.Get("length").Int()
 
-	rom := make([]byte, length)
+	data := make([]byte, length)
 
-	js.CopyBytesToGo(rom, uints8Array)
+	js.CopyBytesToGo(data, uints8Array)
+	rom, err := utils.ReadRom(data, name, "")
+	if err != nil {
+		return map[string]interface{}{"error": err.Error()}
+	}
+	romName = rom.Name
 
 	chip8.Reset()
-	chip8.LoadRom(rom)
+	chip8.Init()
+	chip8.LoadRomAt(rom.Address, rom.Data)
 	romLoaded = true
-	return nil
+
+	romHints = nil
+	if rom.Info == nil {
+		chip8.SetQuirks(cpu.Quirks{VBlankWait: chip8.Quirks().VBlankWait})
+		resolveKeymaps()
+		return nil
+	}
+
+	romHints = keymap.Hints(rom.Info.Keys)
+	resolveKeymaps()
+
+	quirks := rom.Info.Quirks()
+	chip8.SetQuirks(quirks)
+
+	info := map[string]interface{}{
+		"title":    rom.Info.Name(),
+		"tickrate": rom.Info.Tickrate,
+		"vblank":   quirks.VBlankWait,
+	}
+
+	if p, ok := rom.Info.Palette(); ok {
+		info["colors"] = []interface{}{hexColor(p.Color(0)), hexColor(p.Color(1))}
+	}
+
+	return info
 }
 
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// startJS runs the emulator, calling args[0](pixels, width, height) with
+// every changed frame as RGBA pixels in a Uint8Array. The loop is started
+// once, later calls only replace the callback.
 func startJS(this js.Value, args []js.Value) interface{} {
-	renderCb := args[0]
-	videoMemory := args[1]
+	renderCb = args[0]
+	if started {
+		return nil
+	}
+	started = true
 
-	lastTimerUpdate := time.Now()
-	timerInterval := time.Second / 60
+	var pixels js.Value
 
-	lastCycle := time.Now()
-	cycleInterval := time.Second / 500
+	lastFrame := time.Now()
+	frameInterval := time.Second / 60
+	frame := make([]byte, render.WIDTH*render.HEIGHT*4)
 
 	var emulatorLoop js.Func
 	emulatorLoop = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
@@ -76,25 +380,31 @@ func startJS(this js.Value, args []js.Value) interface{} {
 
 		now := time.Now()
 
-		if now.Sub(lastTimerUpdate) >= timerInterval {
-			chip8.UpdateTimers()
-			lastTimerUpdate = now
+		// Don't try to catch up after the tab was in the background
+		if now.Sub(lastFrame) > 5*frameInterval {
+			lastFrame = now.Add(-frameInterval)
 		}
 
-		if now.Sub(lastCycle) >= cycleInterval {
-			chip8.Cycle()
-
-			if chip8.DrawFlag() {
-				chip8.SetDrawFlag(false)
-				video := chip8.GetVideo()
+		draw := false
+		for now.Sub(lastFrame) >= frameInterval {
+			if chip8.RunFrame() || persistence.Active() {
+				persistence.Add(&chip8.Video, palette)
+				draw = true
+			}
+			lastFrame = lastFrame.Add(frameInterval)
+		}
 
-				videoBytes := (*[2048 * 4]byte)(unsafe.Pointer(&video[0]))[:2048*4]
-				js.CopyBytesToJS(videoMemory, videoBytes)
+		if draw {
+			persistence.Fill(frame)
+			img := effects.Apply(frame)
 
-				renderCb.Invoke()
+			// The filters may change the frame size
+			if pixels.IsUndefined() || pixels.Length() != len(img.Pix) {
+				pixels = js.Global().Get("Uint8Array").New(len(img.Pix))
 			}
+			js.CopyBytesToJS(pixels, img.Pix)
 
-			lastCycle = now
+			renderCb.Invoke(pixels, img.Rect.Dx(), img.Rect.Dy())
 		}
 
 		js.Global().Call("requestAnimationFrame", emulatorLoop)